@@ -4,44 +4,31 @@ import (
 	"log"
 
 	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
 )
 
-func handleAdvance(r *rollup.Rollup) bool {
-	advance, err := r.ReadAdvanceState()
-	if err != nil {
-		log.Printf("[echo] failed to read advance: %v", err)
-		return false
-	}
+func handleAdvance(ctx *router.Context) error {
+	log.Printf("[echo] received advance from %s with %d bytes", ctx.Sender().Hex(), len(ctx.Payload()))
 
-	log.Printf("[echo] received advance from %s with %d bytes", advance.MsgSender.Hex(), len(advance.Payload))
-
-	_, err = r.EmitNotice(advance.Payload)
-	if err != nil {
+	if _, err := ctx.Notice(ctx.Payload()); err != nil {
 		log.Printf("[echo] failed to emit notice: %v", err)
-		return false
+		return err
 	}
 
 	log.Printf("[echo] emitted notice with payload")
-	return true
+	return nil
 }
 
-func handleInspect(r *rollup.Rollup) bool {
-	inspect, err := r.ReadInspectState()
-	if err != nil {
-		log.Printf("[echo] failed to read inspect: %v", err)
-		return false
-	}
+func handleInspect(ctx *router.Context) error {
+	log.Printf("[echo] received inspect with %d bytes", len(ctx.Payload()))
 
-	log.Printf("[echo] received inspect with %d bytes", len(inspect.Payload))
-
-	err = r.EmitReport(inspect.Payload)
-	if err != nil {
+	if err := ctx.Report(ctx.Payload()); err != nil {
 		log.Printf("[echo] failed to emit report: %v", err)
-		return false
+		return err
 	}
 
 	log.Printf("[echo] emitted report with payload")
-	return true
+	return nil
 }
 
 func main() {
@@ -51,19 +38,11 @@ func main() {
 	}
 	defer r.Close()
 
-	accept := true
-	for {
-		reqType, _, err := r.Finish(accept)
-		if err != nil {
-			log.Printf("[echo] finish error: %v", err)
-			continue
-		}
+	rt := router.New(r, nil)
+	rt.HandleFallback(handleAdvance)
+	rt.HandleInspect(handleInspect)
 
-		switch reqType {
-		case rollup.RequestTypeAdvance:
-			accept = handleAdvance(r)
-		case rollup.RequestTypeInspect:
-			accept = handleInspect(r)
-		}
+	if err := rt.Run(); err != nil {
+		log.Fatalf("[echo] finish error: %v", err)
 	}
 }
@@ -2,259 +2,47 @@ package main
 
 import (
 	"log"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/henriquemarlon/rollingopher/pkg/ledger"
-	"github.com/henriquemarlon/rollingopher/pkg/parser"
 	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+	"github.com/henriquemarlon/rollingopher/pkg/wallet"
 )
 
-var (
-	EtherPortal         = common.HexToAddress("0xFfdbe43d4c855BF7e0f105c400A50857f53AB044")
-	ERC20Portal         = common.HexToAddress("0x9C21AEb2093C32DDbC53eEF24B873BDCd1aDa1DB")
-	ERC721Portal        = common.HexToAddress("0x237F8DD094C0e47f4236f12b4Fa01d6Dae89fb87")
-	ERC1155SinglePortal = common.HexToAddress("0x7CFB0193Ca87eB6e48056885E026552c3A941FC4")
-	ERC1155BatchPortal  = common.HexToAddress("0xedB53860A6B52bbb7561Ad596416ee9965B055Aa")
-)
-
-var etherAssetID ledger.AssetID
+func handleDeposit(ctx *router.Context, deposit interface{}) error {
+	log.Printf("[handling-assets] input %d: deposit %T from %s", ctx.Metadata.Index, deposit, ctx.Sender().Hex())
+	return nil
+}
 
-func handleAdvance(r *rollup.Rollup, l *ledger.Ledger) bool {
-	advance, err := r.ReadAdvanceState()
+func main() {
+	r, err := rollup.New()
 	if err != nil {
-		log.Printf("[handling-assets] failed to read advance: %v", err)
-		return false
-	}
-
-	msgSender := advance.MsgSender
-	log.Printf("[handling-assets] Received advance from %s", msgSender.Hex())
-
-	var inputType parser.InputType
-	switch msgSender {
-	case EtherPortal:
-		inputType = parser.InputTypeEtherDeposit
-	case ERC20Portal:
-		inputType = parser.InputTypeERC20Deposit
-	case ERC721Portal:
-		inputType = parser.InputTypeERC721Deposit
-	case ERC1155SinglePortal:
-		inputType = parser.InputTypeERC1155SingleDeposit
-	case ERC1155BatchPortal:
-		inputType = parser.InputTypeERC1155BatchDeposit
-	default:
-		inputType = parser.InputTypeAuto
+		log.Fatalf("[handling-assets] failed to create rollup: %v", err)
 	}
+	defer r.Close()
 
-	decodedInput, err := parser.DecodeAdvance(inputType, advance.Payload)
+	l, err := ledger.New()
 	if err != nil {
-		log.Printf("[handling-assets] failed to decode: %v", err)
-		return false
+		log.Fatalf("[handling-assets] failed to create ledger: %v", err)
 	}
+	defer l.Close()
 
-	switch d := decodedInput.(type) {
-	// Ether
-	case *parser.EtherDeposit:
-		accountID, _ := l.RetrieveAccountByAddress(d.Sender, ledger.RetrieveOperationFindOrCreate)
-		l.Deposit(etherAssetID, accountID, d.Amount)
-		log.Printf("[handling-assets] %s deposited %s ether", d.Sender.Hex(), d.Amount)
-		return true
-
-	case *parser.EtherWithdrawal:
-		accountID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		l.Withdraw(etherAssetID, accountID, d.Amount)
-		v := parser.EncodeEtherVoucher(msgSender, d.Amount)
-		r.EmitVoucher(v.Destination, v.Value, v.Payload)
-		log.Printf("[handling-assets] %s withdrew %s ether", msgSender.Hex(), d.Amount)
-		return true
-
-	case *parser.EtherTransfer:
-		fromID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		toID, _ := l.RetrieveAccountByID(d.Receiver, ledger.RetrieveOperationFindOrCreate)
-		l.Transfer(etherAssetID, fromID, toID, d.Amount)
-		log.Printf("[handling-assets] %s transferred %s ether to %s", msgSender.Hex(), d.Amount, d.Receiver.Hex())
-		return true
-
-	case *parser.ERC20Deposit:
-		assetID, _ := l.RetrieveAsset(d.Token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFindOrCreate)
-		accountID, _ := l.RetrieveAccountByAddress(d.Sender, ledger.RetrieveOperationFindOrCreate)
-		l.Deposit(assetID, accountID, d.Amount)
-		log.Printf("[handling-assets] %s deposited %s of %s", d.Sender.Hex(), d.Amount, d.Token.Hex())
-		return true
-
-	case *parser.ERC20Withdrawal:
-		assetID, _ := l.RetrieveAsset(d.Token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFind)
-		accountID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		l.Withdraw(assetID, accountID, d.Amount)
-		v, _ := parser.EncodeERC20Voucher(d.Token, msgSender, d.Amount)
-		r.EmitVoucher(v.Destination, v.Value, v.Payload)
-		log.Printf("[handling-assets] %s withdrew %s of %s", msgSender.Hex(), d.Amount, d.Token.Hex())
-		return true
-
-	case *parser.ERC20Transfer:
-		assetID, _ := l.RetrieveAsset(d.Token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFind)
-		fromID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		toID, _ := l.RetrieveAccountByID(d.Receiver, ledger.RetrieveOperationFindOrCreate)
-		l.Transfer(assetID, fromID, toID, d.Amount)
-		log.Printf("[handling-assets] %s transferred %s of %s to %s", msgSender.Hex(), d.Amount, d.Token.Hex(), d.Receiver.Hex())
-		return true
-
-	case *parser.ERC721Deposit:
-		assetID, _ := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
-		accountID, _ := l.RetrieveAccountByAddress(d.Sender, ledger.RetrieveOperationFindOrCreate)
-		l.Deposit(assetID, accountID, big.NewInt(1))
-		log.Printf("[handling-assets] %s deposited ERC721 %s #%s", d.Sender.Hex(), d.Token.Hex(), d.TokenID)
-		return true
-
-	case *parser.ERC721Withdrawal:
-		assetID, _ := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
-		accountID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		l.Withdraw(assetID, accountID, big.NewInt(1))
-		v, _ := parser.EncodeERC721Voucher(d.Token, advance.AppContract, msgSender, d.TokenID)
-		r.EmitVoucher(v.Destination, v.Value, v.Payload)
-		log.Printf("[handling-assets] %s withdrew ERC721 %s #%s", msgSender.Hex(), d.Token.Hex(), d.TokenID)
-		return true
-
-	case *parser.ERC721Transfer:
-		assetID, _ := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
-		fromID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		toID, _ := l.RetrieveAccountByID(d.Receiver, ledger.RetrieveOperationFindOrCreate)
-		l.Transfer(assetID, fromID, toID, big.NewInt(1))
-		log.Printf("[handling-assets] %s transferred ERC721 %s #%s to %s", msgSender.Hex(), d.Token.Hex(), d.TokenID, d.Receiver.Hex())
-		return true
-
-	case *parser.ERC1155SingleDeposit:
-		assetID, _ := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
-		accountID, _ := l.RetrieveAccountByAddress(d.Sender, ledger.RetrieveOperationFindOrCreate)
-		l.Deposit(assetID, accountID, d.Amount)
-		log.Printf("[handling-assets] %s deposited %s of ERC1155 %s #%s", d.Sender.Hex(), d.Amount, d.Token.Hex(), d.TokenID)
-		return true
-
-	case *parser.ERC1155SingleWithdrawal:
-		assetID, _ := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
-		accountID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		l.Withdraw(assetID, accountID, d.Amount)
-		v, _ := parser.EncodeERC1155SingleVoucher(d.Token, advance.AppContract, msgSender, d.TokenID, d.Amount)
-		r.EmitVoucher(v.Destination, v.Value, v.Payload)
-		log.Printf("[handling-assets] %s withdrew %s of ERC1155 %s #%s", msgSender.Hex(), d.Amount, d.Token.Hex(), d.TokenID)
-		return true
-
-	case *parser.ERC1155SingleTransfer:
-		assetID, _ := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
-		fromID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		toID, _ := l.RetrieveAccountByID(d.Receiver, ledger.RetrieveOperationFindOrCreate)
-		l.Transfer(assetID, fromID, toID, d.Amount)
-		log.Printf("[handling-assets] %s transferred %s of ERC1155 %s #%s to %s", msgSender.Hex(), d.Amount, d.Token.Hex(), d.TokenID, d.Receiver.Hex())
-		return true
-
-	case *parser.ERC1155BatchDeposit:
-		accountID, _ := l.RetrieveAccountByAddress(d.Sender, ledger.RetrieveOperationFindOrCreate)
-		for i, tokenID := range d.TokenIDs {
-			assetID, _ := l.RetrieveAsset(d.Token, tokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
-			l.Deposit(assetID, accountID, d.Amounts[i])
-		}
-		log.Printf("[handling-assets] %s deposited ERC1155 batch from %s", d.Sender.Hex(), d.Token.Hex())
-		return true
-
-	case *parser.ERC1155BatchWithdrawal:
-		accountID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		for i, tokenID := range d.TokenIDs {
-			assetID, _ := l.RetrieveAsset(d.Token, tokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
-			l.Withdraw(assetID, accountID, d.Amounts[i])
-		}
-		v, _ := parser.EncodeERC1155BatchVoucher(d.Token, advance.AppContract, msgSender, d.TokenIDs, d.Amounts)
-		r.EmitVoucher(v.Destination, v.Value, v.Payload)
-		log.Printf("[handling-assets] %s withdrew ERC1155 batch from %s", msgSender.Hex(), d.Token.Hex())
-		return true
-
-	case *parser.ERC1155BatchTransfer:
-		fromID, _ := l.RetrieveAccountByAddress(msgSender, ledger.RetrieveOperationFind)
-		toID, _ := l.RetrieveAccountByID(d.Receiver, ledger.RetrieveOperationFindOrCreate)
-		for i, tokenID := range d.TokenIDs {
-			assetID, _ := l.RetrieveAsset(d.Token, tokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
-			l.Transfer(assetID, fromID, toID, d.Amounts[i])
-		}
-		log.Printf("[handling-assets] %s transferred ERC1155 batch of %s to %s", msgSender.Hex(), d.Token.Hex(), d.Receiver.Hex())
-		return true
+	rt := router.New(r, l)
+	rt.Use(router.Logger(), router.Recoverer())
 
-	default:
-		log.Printf("[handling-assets] unknown input type")
-		return false
+	w := wallet.New()
+	w.OnDeposit = handleDeposit
+	if err := w.Register(rt); err != nil {
+		log.Fatalf("[handling-assets] failed to register wallet: %v", err)
 	}
-}
-
-func handleInspect(r *rollup.Rollup, l *ledger.Ledger) bool {
-	inspect, err := r.ReadInspectState()
-	if err != nil {
-		log.Printf("[handling-assets] failed to read inspect: %v", err)
-		return false
+	if err := rt.HandleLedgerInspect(); err != nil {
+		log.Fatalf("[handling-assets] failed to register ledger queries: %v", err)
 	}
-
-	decoded, inputType, err := parser.DecodeInspect(inspect.Payload)
-	if err != nil {
-		log.Printf("[handling-assets] failed to decode inspect: %v", err)
-		return false
+	if err := rt.HandleSimulation(); err != nil {
+		log.Fatalf("[handling-assets] failed to register simulations: %v", err)
 	}
 
-	switch inputType {
-	case parser.InputTypeBalance, parser.InputTypeBalanceAccount, parser.InputTypeBalanceAccountTokenAddress, parser.InputTypeBalanceAccountTokenAddressID:
-		query := decoded.(*parser.BalanceQuery)
-		accountID, _ := l.RetrieveAccountByID(query.Account, ledger.RetrieveOperationFind)
-		assetID := etherAssetID
-		if query.Token != (common.Address{}) {
-			assetType := ledger.AssetTypeTokenAddress
-			if inputType == parser.InputTypeBalanceAccountTokenAddressID {
-				assetType = ledger.AssetTypeTokenAddressID
-			}
-			assetID, _ = l.RetrieveAsset(query.Token, query.TokenID, assetType, ledger.RetrieveOperationFind)
-		}
-		balance, _ := l.GetBalance(assetID, accountID)
-		report := make([]byte, 32)
-		balance.FillBytes(report)
-		r.EmitReport(report)
-		log.Printf("[handling-assets] balance: %s (type %v)", balance, inputType)
-		return true
-
-	case parser.InputTypeSupply, parser.InputTypeSupplyTokenAddress, parser.InputTypeSupplyTokenAddressID:
-		query := decoded.(*parser.SupplyQuery)
-		assetID := etherAssetID
-		if query.Token != (common.Address{}) {
-			assetType := ledger.AssetTypeTokenAddress
-			if inputType == parser.InputTypeSupplyTokenAddressID {
-				assetType = ledger.AssetTypeTokenAddressID
-			}
-			assetID, _ = l.RetrieveAsset(query.Token, query.TokenID, assetType, ledger.RetrieveOperationFind)
-		}
-		supply, _ := l.GetTotalSupply(assetID)
-		report := make([]byte, 32)
-		supply.FillBytes(report)
-		r.EmitReport(report)
-		log.Printf("[handling-assets] supply: %s (type %v)", supply, inputType)
-		return true
-
-	default:
-		log.Printf("[handling-assets] unknown inspect type: %v", inputType)
-		return false
-	}
-}
-
-func main() {
-	r, _ := rollup.New()
-	defer r.Close()
-
-	l, _ := ledger.New()
-	defer l.Close()
-
-	etherAssetID, _ = l.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFindOrCreate)
-
-	accept := true
-	for {
-		reqType, _, _ := r.Finish(accept)
-		switch reqType {
-		case rollup.RequestTypeAdvance:
-			accept = handleAdvance(r, l)
-		case rollup.RequestTypeInspect:
-			accept = handleInspect(r, l)
-		}
+	if err := rt.Run(); err != nil {
+		log.Fatalf("[handling-assets] finish error: %v", err)
 	}
 }
@@ -2,7 +2,10 @@ module github.com/henriquemarlon/rollingopher
 
 go 1.24.4
 
-require github.com/ethereum/go-ethereum v1.16.7
+require (
+	github.com/ethereum/go-ethereum v1.16.7
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/ProjectZKM/Ziren/crates/go-runtime/zkvm_runtime v0.0.0-20251001021608-1fe7b43fc4d6 // indirect
@@ -0,0 +1,259 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+)
+
+// RoleAdmin is granted to the initial owner and is required to grant or
+// revoke any role, including itself.
+const RoleAdmin = "admin"
+
+// Access keeps role memberships in the ledger: every role is a reserved asset
+// (see router.ReservedAsset) named after it, and holding one unit of it means
+// holding the role. Roles are therefore saved, restored and reset together
+// with balances, and the number of members of a role is its total supply.
+type Access struct {
+	ledger *ledger.Ledger
+}
+
+// New creates an access controller over l. When no account holds RoleAdmin
+// yet, it is granted to owner.
+func New(l *ledger.Ledger, owner common.Address) (*Access, error) {
+	a := &Access{ledger: l}
+
+	count, err := a.MemberCount(RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		if err := a.Grant(RoleAdmin, owner); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// RoleTokenID returns the token ID, under router.ReservedToken, of the ledger
+// asset backing role.
+func RoleTokenID(role string) *big.Int {
+	return router.ReservedTokenID(roleAsset(role))
+}
+
+func (a *Access) HasRole(role string, account common.Address) (bool, error) {
+	assetID, err := a.asset(role, ledger.RetrieveOperationFind)
+	if err != nil {
+		return false, notFound(err)
+	}
+	accountID, err := a.ledger.RetrieveAccountByAddress(account, ledger.RetrieveOperationFind)
+	if err != nil {
+		return false, notFound(err)
+	}
+	balance, err := a.ledger.GetBalance(assetID, accountID)
+	if err != nil {
+		return false, notFound(err)
+	}
+	return balance.Sign() > 0, nil
+}
+
+// MemberCount returns the number of accounts holding role.
+func (a *Access) MemberCount(role string) (uint64, error) {
+	assetID, err := a.asset(role, ledger.RetrieveOperationFind)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	supply, err := a.ledger.GetTotalSupply(assetID)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return supply.Uint64(), nil
+}
+
+// Grant gives role to account. Granting a role that is already held is a
+// no-op.
+func (a *Access) Grant(role string, account common.Address) error {
+	if role == "" {
+		return ErrEmptyRole
+	}
+	ok, err := a.HasRole(role, account)
+	if err != nil || ok {
+		return err
+	}
+
+	assetID, err := a.asset(role, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	accountID, err := a.ledger.RetrieveAccountByAddress(account, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	if err := a.ledger.Deposit(assetID, accountID, big.NewInt(1)); err != nil {
+		return err
+	}
+	log.Printf("[access] granted %q to %s", role, account.Hex())
+	return nil
+}
+
+// Revoke takes role from account. Revoking a role that is not held is a
+// no-op, and RoleAdmin cannot be taken from its last holder, which would
+// leave no one able to grant roles.
+func (a *Access) Revoke(role string, account common.Address) error {
+	ok, err := a.HasRole(role, account)
+	if err != nil || !ok {
+		return err
+	}
+	if role == RoleAdmin {
+		count, err := a.MemberCount(RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return fmt.Errorf("%w: %s", ErrLastAdmin, account.Hex())
+		}
+	}
+
+	assetID, err := a.asset(role, ledger.RetrieveOperationFind)
+	if err != nil {
+		return err
+	}
+	accountID, err := a.ledger.RetrieveAccountByAddress(account, ledger.RetrieveOperationFind)
+	if err != nil {
+		return err
+	}
+	if err := a.ledger.Withdraw(assetID, accountID, big.NewInt(1)); err != nil {
+		return err
+	}
+	log.Printf("[access] revoked %q from %s", role, account.Hex())
+	return nil
+}
+
+// RequireRole rejects advances whose sender does not hold role, like
+// router.AllowSenders.
+func (a *Access) RequireRole(role string) router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(ctx *router.Context) error {
+			if ctx.Advance == nil {
+				return next(ctx)
+			}
+			ok, err := a.on(ctx).HasRole(role, ctx.Sender())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%w: %s does not have %q", ErrMissingRole, ctx.Sender().Hex(), role)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// Register installs the role management advances, restricted to RoleAdmin
+// holders,
+//
+//	grantRole(string role, address account)
+//	revokeRole(string role, address account)
+//
+// along with renounceRole(string role), which any account may send for its
+// own roles, and the inspect queries
+//
+//	access_hasRole/{role}/{account}
+//	access_getRoleMemberCount/{role}
+//
+// which answer like the queries of router.HandleLedgerInspect.
+func (a *Access) Register(rt *router.Router, mws ...router.Middleware) error {
+	admin := append(append([]router.Middleware{}, mws...), a.RequireRole(RoleAdmin))
+
+	if err := rt.HandleMethod("grantRole(string,address)", a.handleGrant, admin...); err != nil {
+		return err
+	}
+	if err := rt.HandleMethod("revokeRole(string,address)", a.handleRevoke, admin...); err != nil {
+		return err
+	}
+	if err := rt.HandleMethod("renounceRole(string)", a.handleRenounce, mws...); err != nil {
+		return err
+	}
+
+	rt.HandleInspectRoute("access_hasRole/{role}/{account}", a.handleHasRole, mws...)
+	rt.HandleInspectRoute("access_getRoleMemberCount/{role}", a.handleMemberCount, mws...)
+	return nil
+}
+
+func (a *Access) handleGrant(ctx *router.Context, role string, account common.Address) error {
+	return a.on(ctx).Grant(role, account)
+}
+
+func (a *Access) handleRevoke(ctx *router.Context, role string, account common.Address) error {
+	return a.on(ctx).Revoke(role, account)
+}
+
+func (a *Access) handleRenounce(ctx *router.Context, role string) error {
+	return a.on(ctx).Revoke(role, ctx.Sender())
+}
+
+func (a *Access) handleHasRole(ctx *router.Context) error {
+	role, err := ctx.Params.Get("role")
+	if err != nil {
+		return err
+	}
+	account, err := ctx.Params.Address("account")
+	if err != nil {
+		return err
+	}
+	ok, err := a.on(ctx).HasRole(role, account)
+	if err != nil {
+		return err
+	}
+
+	report := make([]byte, 32)
+	if ok {
+		report[31] = 1
+	}
+	return ctx.Report(report)
+}
+
+func (a *Access) handleMemberCount(ctx *router.Context) error {
+	role, err := ctx.Params.Get("role")
+	if err != nil {
+		return err
+	}
+	count, err := a.on(ctx).MemberCount(role)
+	if err != nil {
+		return err
+	}
+
+	report := make([]byte, 32)
+	new(big.Int).SetUint64(count).FillBytes(report)
+	return ctx.Report(report)
+}
+
+// on returns the controller over the ledger of ctx, which differs from the
+// one a was created with when the request runs against a forked ledger.
+func (a *Access) on(ctx *router.Context) *Access {
+	if ctx.Ledger == nil || ctx.Ledger == a.ledger {
+		return a
+	}
+	return &Access{ledger: ctx.Ledger}
+}
+
+func (a *Access) asset(role string, op ledger.RetrieveOperation) (ledger.AssetID, error) {
+	return router.ReservedAsset(a.ledger, roleAsset(role), op)
+}
+
+func roleAsset(role string) string {
+	return "role:" + role
+}
+
+// notFound treats unknown roles and accounts as holding nothing.
+func notFound(err error) error {
+	if errors.Is(err, ledger.ErrAccountNotFound) || errors.Is(err, ledger.ErrAssetNotFound) {
+		return nil
+	}
+	return err
+}
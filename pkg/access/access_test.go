@@ -0,0 +1,238 @@
+//go:build !riscv64
+
+package access
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+	"github.com/henriquemarlon/rollingopher/pkg/tester"
+	"github.com/henriquemarlon/rollingopher/pkg/wallet"
+)
+
+var (
+	owner = common.HexToAddress("0x00000000000000000000000000000000000000a0")
+	alice = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+)
+
+func newAccess(t *testing.T) (*Access, *rollup.Rollup, *ledger.Ledger) {
+	t.Helper()
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ledger.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(l, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, r, l
+}
+
+func hasRole(t *testing.T, a *Access, role string, account common.Address) bool {
+	t.Helper()
+	ok, err := a.HasRole(role, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+func TestGrantAndRevoke(t *testing.T) {
+	a, _, l := newAccess(t)
+
+	if !hasRole(t, a, RoleAdmin, owner) {
+		t.Fatal("expected the owner to be admin")
+	}
+	if hasRole(t, a, "minter", alice) {
+		t.Fatal("expected alice to start without roles")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := a.Grant("minter", alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !hasRole(t, a, "minter", alice) {
+		t.Fatal("expected alice to be minter")
+	}
+	if n, err := a.MemberCount("minter"); err != nil || n != 1 {
+		t.Fatalf("expected granting twice to count once, got %d, %v", n, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := a.Revoke("minter", alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hasRole(t, a, "minter", alice) {
+		t.Fatal("expected the role to be revoked")
+	}
+	if err := a.Grant("", alice); !errors.Is(err, ErrEmptyRole) {
+		t.Fatalf("expected %v, got %v", ErrEmptyRole, err)
+	}
+
+	// A controller over the same ledger sees the same roles and keeps the
+	// existing admin.
+	b, err := New(l, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasRole(t, b, RoleAdmin, owner) || hasRole(t, b, RoleAdmin, alice) {
+		t.Fatal("expected the roles stored in the ledger to be kept")
+	}
+}
+
+func TestLastAdmin(t *testing.T) {
+	a, r, l := newAccess(t)
+	ctx := func(sender common.Address) *router.Context {
+		return router.NewAdvanceContext(r, l, &rollup.Advance{Metadata: rollup.Metadata{MsgSender: sender}})
+	}
+
+	if err := a.Revoke(RoleAdmin, owner); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected revoking the last admin to fail with %v, got %v", ErrLastAdmin, err)
+	}
+	if err := a.handleRenounce(ctx(owner), RoleAdmin); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected renouncing the last admin to fail with %v, got %v", ErrLastAdmin, err)
+	}
+	if !hasRole(t, a, RoleAdmin, owner) {
+		t.Fatal("expected the owner to stay admin")
+	}
+
+	// With a second admin, either one may go, but not both.
+	if err := a.Grant(RoleAdmin, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.handleRenounce(ctx(owner), RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Revoke(RoleAdmin, alice); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected %v, got %v", ErrLastAdmin, err)
+	}
+	if hasRole(t, a, RoleAdmin, owner) || !hasRole(t, a, RoleAdmin, alice) {
+		t.Fatal("expected alice to be the only admin")
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	a, r, l := newAccess(t)
+	h := a.RequireRole(RoleAdmin)(func(ctx *router.Context) error { return nil })
+
+	advance := func(sender common.Address) *router.Context {
+		return router.NewAdvanceContext(r, l, &rollup.Advance{Metadata: rollup.Metadata{MsgSender: sender}})
+	}
+	if err := h(advance(owner)); err != nil {
+		t.Fatalf("expected the admin to pass, got %v", err)
+	}
+	if err := h(advance(alice)); !errors.Is(err, ErrMissingRole) {
+		t.Fatalf("expected %v, got %v", ErrMissingRole, err)
+	}
+	if err := h(router.NewInspectContext(r, l, &rollup.Inspect{})); err != nil {
+		t.Fatalf("expected inspects to pass, got %v", err)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	a, r, l := newAccess(t)
+	ctx := router.NewAdvanceContext(r, l, &rollup.Advance{Metadata: rollup.Metadata{MsgSender: alice}})
+
+	if err := a.handleGrant(ctx, "minter", alice); err != nil {
+		t.Fatal(err)
+	}
+	query := router.NewInspectContext(r, l, &rollup.Inspect{})
+	query.Params = router.Params{"role": "minter", "account": alice.Hex()}
+	if err := a.handleHasRole(query); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.handleMemberCount(query); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.handleRenounce(ctx, "minter"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.handleHasRole(query); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := r.Reports()
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(reports))
+	}
+	for i, want := range []byte{1, 1, 0} {
+		if got := reports[i].Payload; len(got) != 32 || got[31] != want {
+			t.Fatalf("report %d: got %x, want %d", i, got, want)
+		}
+	}
+}
+
+func TestHandlersFollowTheContextLedger(t *testing.T) {
+	a, r, l := newAccess(t)
+	fork, err := l.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := router.NewAdvanceContext(r, fork, &rollup.Advance{Metadata: rollup.Metadata{MsgSender: owner}})
+	if err := a.RequireRole(RoleAdmin)(func(ctx *router.Context) error {
+		return a.handleGrant(ctx, "minter", alice)
+	})(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if hasRole(t, a, "minter", alice) {
+		t.Fatal("expected the grant to stay in the forked ledger")
+	}
+	if ok, err := a.on(ctx).HasRole("minter", alice); err != nil || !ok {
+		t.Fatalf("expected the grant in the forked ledger, got %v, %v", ok, err)
+	}
+}
+
+func TestRolesCannotBeMovedByTheWallet(t *testing.T) {
+	var a *Access
+	tt := tester.New(t, func(r rollup.Interface, l *ledger.Ledger) error {
+		var err error
+		if a, err = New(l, owner); err != nil {
+			return err
+		}
+		rt := router.New(r, l)
+		if err := wallet.New().Register(rt); err != nil {
+			return err
+		}
+		if err := a.Register(rt); err != nil {
+			return err
+		}
+		return rt.Run()
+	})
+
+	transfer := parser.EncodeERC1155SingleTransfer(&parser.ERC1155SingleTransfer{
+		Token:    router.ReservedToken,
+		Receiver: common.BytesToHash(alice.Bytes()),
+		TokenID:  RoleTokenID(RoleAdmin),
+		Amount:   big.NewInt(1),
+	})
+	res := tt.Advance(owner, transfer).ExpectRejected()
+	if len(res.Reports) != 1 || !bytes.Contains(res.Reports[0].Payload, []byte(router.ErrReservedToken.Error())) {
+		t.Fatalf("expected a %q report, got %v", router.ErrReservedToken, res.Reports)
+	}
+
+	withdrawal := parser.EncodeERC1155SingleWithdrawal(&parser.ERC1155SingleWithdrawal{
+		Token:   router.ReservedToken,
+		TokenID: RoleTokenID(RoleAdmin),
+		Amount:  big.NewInt(1),
+	})
+	tt.Advance(owner, withdrawal).ExpectRejected().ExpectOutputs(0, 0, 0)
+
+	if !hasRole(t, a, RoleAdmin, owner) || hasRole(t, a, RoleAdmin, alice) {
+		t.Fatal("the admin role moved through the wallet")
+	}
+}
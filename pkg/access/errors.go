@@ -0,0 +1,9 @@
+package access
+
+import "errors"
+
+var (
+	ErrMissingRole = errors.New("missing role")
+	ErrEmptyRole   = errors.New("empty role name")
+	ErrLastAdmin   = errors.New("cannot remove the last admin")
+)
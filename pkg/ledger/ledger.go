@@ -47,6 +47,12 @@ func (l *Ledger) Reset() error {
 	return nil
 }
 
+// Fork returns l itself, as libcma cannot copy a ledger. That is only safe
+// within an inspect, whose changes the machine never keeps.
+func (l *Ledger) Fork() (*Ledger, error) {
+	return l, nil
+}
+
 // TODO: func (l *Ledger) Load(filepath string) error
 // TODO: func (l *Ledger) Save(filepath string) error
 
@@ -4,6 +4,7 @@ package ledger
 
 import (
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -14,12 +15,6 @@ type assetKey struct {
 	tokenID      string
 }
 
-type accountKey struct {
-	address   common.Address
-	accountID common.Hash
-	keyType   AccountType
-}
-
 type Ledger struct {
 	mu sync.Mutex
 
@@ -27,10 +22,12 @@ type Ledger struct {
 	nextAccountID InternalAccountID
 
 	assets   map[assetKey]AssetID
-	accounts map[accountKey]InternalAccountID
+	accounts map[common.Hash]InternalAccountID
 
 	balances map[AssetID]map[InternalAccountID]*big.Int
 	supplies map[AssetID]*big.Int
+
+	snapshot *Ledger
 }
 
 func New() (*Ledger, error) {
@@ -38,7 +35,7 @@ func New() (*Ledger, error) {
 		nextAssetID:   1,
 		nextAccountID: 1,
 		assets:        make(map[assetKey]AssetID),
-		accounts:      make(map[accountKey]InternalAccountID),
+		accounts:      make(map[common.Hash]InternalAccountID),
 		balances:      make(map[AssetID]map[InternalAccountID]*big.Int),
 		supplies:      make(map[AssetID]*big.Int),
 	}, nil
@@ -55,9 +52,77 @@ func (l *Ledger) Reset() error {
 	l.nextAssetID = 1
 	l.nextAccountID = 1
 	l.assets = make(map[assetKey]AssetID)
-	l.accounts = make(map[accountKey]InternalAccountID)
+	l.accounts = make(map[common.Hash]InternalAccountID)
 	l.balances = make(map[AssetID]map[InternalAccountID]*big.Int)
 	l.supplies = make(map[AssetID]*big.Int)
+	l.snapshot = nil
+	return nil
+}
+
+// Fork returns an independent copy of the ledger. Changes to either one are
+// not seen by the other.
+func (l *Ledger) Fork() (*Ledger, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fork := &Ledger{
+		nextAssetID:   l.nextAssetID,
+		nextAccountID: l.nextAccountID,
+		assets:        make(map[assetKey]AssetID, len(l.assets)),
+		accounts:      make(map[common.Hash]InternalAccountID, len(l.accounts)),
+		balances:      make(map[AssetID]map[InternalAccountID]*big.Int, len(l.balances)),
+		supplies:      make(map[AssetID]*big.Int, len(l.supplies)),
+	}
+	for key, id := range l.assets {
+		fork.assets[key] = id
+	}
+	for key, id := range l.accounts {
+		fork.accounts[key] = id
+	}
+	// Amounts are replaced rather than updated in place, so they can be shared.
+	for assetID, balances := range l.balances {
+		fork.balances[assetID] = make(map[InternalAccountID]*big.Int, len(balances))
+		for accountID, balance := range balances {
+			fork.balances[assetID][accountID] = balance
+		}
+	}
+	for assetID, supply := range l.supplies {
+		fork.supplies[assetID] = supply
+	}
+	return fork, nil
+}
+
+// Snapshot saves the current state for a later Restore, replacing any
+// previous snapshot.
+func (l *Ledger) Snapshot() error {
+	snapshot, err := l.Fork()
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.snapshot = snapshot
+	return nil
+}
+
+// Restore brings the ledger back to the state saved by the last Snapshot,
+// which is then dropped. Without a snapshot it does nothing.
+func (l *Ledger) Restore() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.snapshot
+	if s == nil {
+		return nil
+	}
+	l.nextAssetID = s.nextAssetID
+	l.nextAccountID = s.nextAccountID
+	l.assets = s.assets
+	l.accounts = s.accounts
+	l.balances = s.balances
+	l.supplies = s.supplies
+	l.snapshot = nil
 	return nil
 }
 
@@ -91,39 +156,17 @@ func (l *Ledger) RetrieveAsset(tokenAddress common.Address, tokenID *big.Int, as
 	return id, nil
 }
 
+// RetrieveAccountByAddress retrieves the account whose ID is address
+// left-padded to 32 bytes, the ID clients name a wallet by in transfers.
 func (l *Ledger) RetrieveAccountByAddress(address common.Address, op RetrieveOperation) (InternalAccountID, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	key := accountKey{
-		address: address,
-		keyType: AccountTypeWalletAddress,
-	}
-
-	if id, exists := l.accounts[key]; exists {
-		return id, nil
-	}
-
-	if op == RetrieveOperationFind {
-		return 0, ErrAccountNotFound
-	}
-
-	id := l.nextAccountID
-	l.nextAccountID++
-	l.accounts[key] = id
-	return id, nil
+	return l.RetrieveAccountByID(common.BytesToHash(address.Bytes()), op)
 }
 
 func (l *Ledger) RetrieveAccountByID(accountID common.Hash, op RetrieveOperation) (InternalAccountID, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	key := accountKey{
-		accountID: accountID,
-		keyType:   AccountTypeAccountID,
-	}
-
-	if id, exists := l.accounts[key]; exists {
+	if id, exists := l.accounts[accountID]; exists {
 		return id, nil
 	}
 
@@ -133,7 +176,7 @@ func (l *Ledger) RetrieveAccountByID(accountID common.Hash, op RetrieveOperation
 
 	id := l.nextAccountID
 	l.nextAccountID++
-	l.accounts[key] = id
+	l.accounts[accountID] = id
 	return id, nil
 }
 
@@ -224,6 +267,56 @@ func (l *Ledger) GetBalance(assetID AssetID, accountID InternalAccountID) (*big.
 	return new(big.Int).Set(balance), nil
 }
 
+// Assets lists every asset of the ledger, ordered by ID. Only the mock ledger
+// can be enumerated.
+func (l *Ledger) Assets() ([]Asset, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	assets := make([]Asset, 0, len(l.assets))
+	for key, id := range l.assets {
+		asset := Asset{ID: id, Token: key.tokenAddress}
+		if key.tokenID != "" {
+			asset.TokenID, _ = new(big.Int).SetString(key.tokenID, 10)
+		}
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].ID < assets[j].ID })
+	return assets, nil
+}
+
+// Accounts lists every account of the ledger, ordered by internal ID, like
+// Assets.
+func (l *Ledger) Accounts() ([]Account, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	accounts := make([]Account, 0, len(l.accounts))
+	for accountID, id := range l.accounts {
+		accounts = append(accounts, Account{ID: id, AccountID: accountID})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, nil
+}
+
+// Balances returns the balance of every account that ever held the asset,
+// like Assets.
+func (l *Ledger) Balances(assetID AssetID) (map[InternalAccountID]*big.Int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	balances, exists := l.balances[assetID]
+	if !exists {
+		return nil, ErrAssetNotFound
+	}
+
+	copied := make(map[InternalAccountID]*big.Int, len(balances))
+	for accountID, balance := range balances {
+		copied[accountID] = new(big.Int).Set(balance)
+	}
+	return copied, nil
+}
+
 func (l *Ledger) GetTotalSupply(assetID AssetID) (*big.Int, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
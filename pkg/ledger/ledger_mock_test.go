@@ -0,0 +1,206 @@
+//go:build !riscv64
+
+package ledger
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFork(t *testing.T) {
+	l, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetID, err := l.RetrieveAsset(common.Address{}, nil, AssetTypeID, RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountID, err := l.RetrieveAccountByAddress(common.HexToAddress("0xa1"), RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Deposit(assetID, accountID, big.NewInt(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	fork, err := l.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fork.Withdraw(assetID, accountID, big.NewInt(2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fork.RetrieveAccountByAddress(common.HexToAddress("0xa2"), RetrieveOperationFindOrCreate); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Deposit(assetID, accountID, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		l    *Ledger
+		want int64
+	}{{l, 6}, {fork, 3}} {
+		balance, err := tc.l.GetBalance(assetID, accountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		supply, err := tc.l.GetTotalSupply(assetID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance.Int64() != tc.want || supply.Int64() != tc.want {
+			t.Fatalf("expected a balance and supply of %d, got %s and %s", tc.want, balance, supply)
+		}
+	}
+	if _, err := l.RetrieveAccountByAddress(common.HexToAddress("0xa2"), RetrieveOperationFind); err != ErrAccountNotFound {
+		t.Fatalf("expected accounts created in the fork to stay there, got %v", err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	l, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Restore(); err != nil {
+		t.Fatalf("expected restoring without a snapshot to do nothing, got %v", err)
+	}
+
+	if err := l.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.RetrieveAccountByAddress(common.HexToAddress("0xa1"), RetrieveOperationFindOrCreate); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.RetrieveAccountByAddress(common.HexToAddress("0xa1"), RetrieveOperationFind); err != ErrAccountNotFound {
+		t.Fatalf("expected the account to be gone after Restore, got %v", err)
+	}
+}
+
+func TestEnumeration(t *testing.T) {
+	l, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := common.HexToAddress("0xcc")
+	ether, err := l.RetrieveAsset(common.Address{}, nil, AssetTypeID, RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nft, err := l.RetrieveAsset(token, big.NewInt(7), AssetTypeTokenAddressID, RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := l.RetrieveAccountByAddress(common.HexToAddress("0xa1"), RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Deposit(nft, alice, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	assets, err := l.Assets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 || assets[0].ID != ether || assets[1].ID != nft || assets[1].Token != token || assets[1].TokenID.Int64() != 7 {
+		t.Fatalf("unexpected assets %+v", assets)
+	}
+
+	accounts, err := l.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != alice || accounts[0].AccountID != common.BytesToHash(common.HexToAddress("0xa1").Bytes()) {
+		t.Fatalf("unexpected accounts %+v", accounts)
+	}
+
+	balances, err := l.Balances(nft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 1 || balances[alice].Int64() != 1 {
+		t.Fatalf("unexpected balances %v", balances)
+	}
+	if _, err := l.Balances(AssetID(99)); err != ErrAssetNotFound {
+		t.Fatalf("expected %v, got %v", ErrAssetNotFound, err)
+	}
+}
+
+func TestWalletAccountsAreLeftPaddedAccountIDs(t *testing.T) {
+	l, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+
+	wallet, err := l.RetrieveAccountByAddress(address, RetrieveOperationCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID, err := l.RetrieveAccountByID(common.BytesToHash(address.Bytes()), RetrieveOperationFind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byID != wallet {
+		t.Fatalf("expected the left-padded address to name account %d, got %d", wallet, byID)
+	}
+
+	// Right-padded, the address is an unrelated account ID.
+	rightPadded := common.BytesToHash(common.RightPadBytes(address.Bytes(), 32))
+	if _, err := l.RetrieveAccountByID(rightPadded, RetrieveOperationFind); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("expected %v, got %v", ErrAccountNotFound, err)
+	}
+
+	accounts, err := l.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != wallet || accounts[0].AccountID != common.BytesToHash(address.Bytes()) {
+		t.Fatalf("unexpected accounts %+v", accounts)
+	}
+}
+
+func TestTransferToLeftPaddedAddressReachesTheWallet(t *testing.T) {
+	l, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	bob := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+
+	assetID, err := l.RetrieveAsset(common.Address{}, nil, AssetTypeID, RetrieveOperationCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := l.RetrieveAccountByAddress(alice, RetrieveOperationCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Deposit(assetID, from, big.NewInt(10)); err != nil {
+		t.Fatal(err)
+	}
+	to, err := l.RetrieveAccountByID(common.BytesToHash(bob.Bytes()), RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Transfer(assetID, from, to, big.NewInt(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	wallet, err := l.RetrieveAccountByAddress(bob, RetrieveOperationFind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Withdraw(assetID, wallet, big.NewInt(4)); err != nil {
+		t.Fatalf("bob could not withdraw the transferred funds: %v", err)
+	}
+}
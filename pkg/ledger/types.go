@@ -1,5 +1,11 @@
 package ledger
 
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
 type AssetID uint64
 
 type InternalAccountID uint64
@@ -20,6 +26,20 @@ const (
 	AccountTypeAccountID
 )
 
+// Asset is an asset listed by Ledger.Assets. TokenID is nil for assets
+// retrieved without one.
+type Asset struct {
+	ID      AssetID
+	Token   common.Address
+	TokenID *big.Int
+}
+
+// Account is an account listed by Ledger.Accounts.
+type Account struct {
+	ID        InternalAccountID
+	AccountID common.Hash
+}
+
 type RetrieveOperation int
 
 const (
@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Encoders build the payloads read by the Decode functions, so that
+// DecodeAdvance(t, Encode...(x)) gives back x. Portal deposits follow the
+// layout of the Cartesi portals. Ledger inputs start with their selector and
+// ABI-encoded fixed arguments; the ExecLayerData of single-asset inputs is
+// appended as is, while batch inputs carry it as a trailing ABI bytes
+// argument.
+
+var (
+	uint256Type, _      = abi.NewType("uint256", "", nil)
+	uint256ArrayType, _ = abi.NewType("uint256[]", "", nil)
+	addressType, _      = abi.NewType("address", "", nil)
+	bytes32Type, _      = abi.NewType("bytes32", "", nil)
+	bytesType, _        = abi.NewType("bytes", "", nil)
+
+	batchDepositArguments = abi.Arguments{
+		{Type: uint256ArrayType}, {Type: uint256ArrayType}, {Type: bytesType}, {Type: bytesType},
+	}
+	batchWithdrawalArguments = abi.Arguments{
+		{Type: addressType}, {Type: uint256ArrayType}, {Type: uint256ArrayType}, {Type: bytesType},
+	}
+	batchTransferArguments = abi.Arguments{
+		{Type: addressType}, {Type: bytes32Type}, {Type: uint256ArrayType}, {Type: uint256ArrayType}, {Type: bytesType},
+	}
+)
+
+// Portals
+
+func EncodeEtherDeposit(d *EtherDeposit) []byte {
+	return concat(d.Sender.Bytes(), word(d.Amount), d.ExecLayerData)
+}
+
+func EncodeERC20Deposit(d *ERC20Deposit) []byte {
+	return concat(d.Token.Bytes(), d.Sender.Bytes(), word(d.Amount), d.ExecLayerData)
+}
+
+func EncodeERC721Deposit(d *ERC721Deposit) []byte {
+	return concat(d.Token.Bytes(), d.Sender.Bytes(), word(d.TokenID), d.ExecLayerData)
+}
+
+func EncodeERC1155SingleDeposit(d *ERC1155SingleDeposit) []byte {
+	return concat(d.Token.Bytes(), d.Sender.Bytes(), word(d.TokenID), word(d.Amount), d.ExecLayerData)
+}
+
+func EncodeERC1155BatchDeposit(d *ERC1155BatchDeposit) ([]byte, error) {
+	data, err := batchDepositArguments.Pack(bigs(d.TokenIDs), bigs(d.Amounts), bytesOrEmpty(d.BaseLayerData), bytesOrEmpty(d.ExecLayerData))
+	if err != nil {
+		return nil, err
+	}
+	return concat(d.Token.Bytes(), d.Sender.Bytes(), data), nil
+}
+
+// Ledger
+
+func EncodeEtherWithdrawal(w *EtherWithdrawal) []byte {
+	return concat(selector(SelectorWithdrawEther), word(w.Amount), w.ExecLayerData)
+}
+
+func EncodeERC20Withdrawal(w *ERC20Withdrawal) []byte {
+	return concat(selector(SelectorWithdrawERC20), addressWord(w.Token), word(w.Amount), w.ExecLayerData)
+}
+
+func EncodeERC721Withdrawal(w *ERC721Withdrawal) []byte {
+	return concat(selector(SelectorWithdrawERC721), addressWord(w.Token), word(w.TokenID), w.ExecLayerData)
+}
+
+func EncodeERC1155SingleWithdrawal(w *ERC1155SingleWithdrawal) []byte {
+	return concat(selector(SelectorWithdrawERC1155Single), addressWord(w.Token), word(w.TokenID), word(w.Amount), w.ExecLayerData)
+}
+
+func EncodeERC1155BatchWithdrawal(w *ERC1155BatchWithdrawal) ([]byte, error) {
+	data, err := batchWithdrawalArguments.Pack(w.Token, bigs(w.TokenIDs), bigs(w.Amounts), bytesOrEmpty(w.ExecLayerData))
+	if err != nil {
+		return nil, err
+	}
+	return concat(selector(SelectorWithdrawERC1155Batch), data), nil
+}
+
+func EncodeEtherTransfer(t *EtherTransfer) []byte {
+	return concat(selector(SelectorTransferEther), t.Receiver.Bytes(), word(t.Amount), t.ExecLayerData)
+}
+
+func EncodeERC20Transfer(t *ERC20Transfer) []byte {
+	return concat(selector(SelectorTransferERC20), addressWord(t.Token), t.Receiver.Bytes(), word(t.Amount), t.ExecLayerData)
+}
+
+func EncodeERC721Transfer(t *ERC721Transfer) []byte {
+	return concat(selector(SelectorTransferERC721), addressWord(t.Token), t.Receiver.Bytes(), word(t.TokenID), t.ExecLayerData)
+}
+
+func EncodeERC1155SingleTransfer(t *ERC1155SingleTransfer) []byte {
+	return concat(selector(SelectorTransferERC1155Single), addressWord(t.Token), t.Receiver.Bytes(), word(t.TokenID), word(t.Amount), t.ExecLayerData)
+}
+
+func EncodeERC1155BatchTransfer(t *ERC1155BatchTransfer) ([]byte, error) {
+	data, err := batchTransferArguments.Pack(t.Token, [32]byte(t.Receiver), bigs(t.TokenIDs), bigs(t.Amounts), bytesOrEmpty(t.ExecLayerData))
+	if err != nil {
+		return nil, err
+	}
+	return concat(selector(SelectorTransferERC1155Batch), data), nil
+}
+
+// EncodeAdvance encodes any of the input structs returned by DecodeAdvance.
+func EncodeAdvance(input interface{}) ([]byte, error) {
+	switch in := input.(type) {
+	case *EtherDeposit:
+		return EncodeEtherDeposit(in), nil
+	case *ERC20Deposit:
+		return EncodeERC20Deposit(in), nil
+	case *ERC721Deposit:
+		return EncodeERC721Deposit(in), nil
+	case *ERC1155SingleDeposit:
+		return EncodeERC1155SingleDeposit(in), nil
+	case *ERC1155BatchDeposit:
+		return EncodeERC1155BatchDeposit(in)
+	case *EtherWithdrawal:
+		return EncodeEtherWithdrawal(in), nil
+	case *ERC20Withdrawal:
+		return EncodeERC20Withdrawal(in), nil
+	case *ERC721Withdrawal:
+		return EncodeERC721Withdrawal(in), nil
+	case *ERC1155SingleWithdrawal:
+		return EncodeERC1155SingleWithdrawal(in), nil
+	case *ERC1155BatchWithdrawal:
+		return EncodeERC1155BatchWithdrawal(in)
+	case *EtherTransfer:
+		return EncodeEtherTransfer(in), nil
+	case *ERC20Transfer:
+		return EncodeERC20Transfer(in), nil
+	case *ERC721Transfer:
+		return EncodeERC721Transfer(in), nil
+	case *ERC1155SingleTransfer:
+		return EncodeERC1155SingleTransfer(in), nil
+	case *ERC1155BatchTransfer:
+		return EncodeERC1155BatchTransfer(in)
+	default:
+		return nil, ErrUnknownInputType
+	}
+}
+
+// Inspect
+
+func EncodeInspectRequest(method string, params ...string) ([]byte, error) {
+	if params == nil {
+		params = []string{}
+	}
+	return json.Marshal(&InspectRequest{Method: method, Params: params})
+}
+
+// EncodeBalanceRequest builds a ledger_getBalance request for DecodeInspect.
+// The token is left out when tokenID is nil and token is the zero address.
+func EncodeBalanceRequest(account common.Hash, token common.Address, tokenID *big.Int) ([]byte, error) {
+	params := []string{account.Hex()}
+	if tokenID != nil || token != (common.Address{}) {
+		params = append(params, token.Hex())
+	}
+	if tokenID != nil {
+		params = append(params, tokenID.String())
+	}
+	return EncodeInspectRequest("ledger_getBalance", params...)
+}
+
+// EncodeSupplyRequest builds a ledger_getTotalSupply request for
+// DecodeInspect, leaving the token out like EncodeBalanceRequest.
+func EncodeSupplyRequest(token common.Address, tokenID *big.Int) ([]byte, error) {
+	var params []string
+	if tokenID != nil || token != (common.Address{}) {
+		params = append(params, token.Hex())
+	}
+	if tokenID != nil {
+		params = append(params, tokenID.String())
+	}
+	return EncodeInspectRequest("ledger_getTotalSupply", params...)
+}
+
+// EncodeBalanceQuery builds the binary query read by DecodeBalanceQuery.
+func EncodeBalanceQuery(q *BalanceQuery, inputType InputType) ([]byte, error) {
+	switch inputType {
+	case InputTypeBalance:
+		return []byte{}, nil
+	case InputTypeBalanceAccount:
+		return q.Account.Bytes(), nil
+	case InputTypeBalanceAccountTokenAddress:
+		return concat(q.Account.Bytes(), q.Token.Bytes()), nil
+	case InputTypeBalanceAccountTokenAddressID:
+		return concat(q.Account.Bytes(), q.Token.Bytes(), word(q.TokenID), q.ExecLayerData), nil
+	default:
+		return nil, ErrIncompatibleInput
+	}
+}
+
+// EncodeSupplyQuery builds the binary query read by DecodeSupplyQuery.
+func EncodeSupplyQuery(q *SupplyQuery, inputType InputType) ([]byte, error) {
+	switch inputType {
+	case InputTypeSupply:
+		return []byte{}, nil
+	case InputTypeSupplyTokenAddress:
+		return q.Token.Bytes(), nil
+	case InputTypeSupplyTokenAddressID:
+		return concat(q.Token.Bytes(), word(q.TokenID), q.ExecLayerData), nil
+	default:
+		return nil, ErrIncompatibleInput
+	}
+}
+
+func selector(s uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, s)
+	return b
+}
+
+// word encodes a uint256, treating nil as zero.
+func word(n *big.Int) []byte {
+	if n == nil {
+		return make([]byte, 32)
+	}
+	return math.U256Bytes(new(big.Int).Set(n))
+}
+
+func addressWord(a common.Address) []byte {
+	return common.LeftPadBytes(a.Bytes(), 32)
+}
+
+func bigs(ns []*big.Int) []*big.Int {
+	if ns == nil {
+		return []*big.Int{}
+	}
+	return ns
+}
+
+func bytesOrEmpty(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	var n int
+	for _, p := range parts {
+		n += len(p)
+	}
+	out := make([]byte, 0, n)
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"encoding/binary"
 	"encoding/json"
+	"math"
 	"math/big"
 	"strings"
 
@@ -196,14 +197,21 @@ func DecodeAdvanceAuto(payload []byte) (interface{}, error) {
 	}
 }
 
-func DecodeInspect(payload []byte) (interface{}, InputType, error) {
-	var req struct {
-		Method string   `json:"method"`
-		Params []string `json:"params"`
+func DecodeInspectRequest(payload []byte) (*InspectRequest, error) {
+	req := &InspectRequest{}
+	if err := json.Unmarshal(payload, req); err != nil {
+		return nil, ErrMalformedInput
 	}
+	if req.Method == "" {
+		return nil, ErrMalformedInput
+	}
+	return req, nil
+}
 
-	if err := json.Unmarshal(payload, &req); err != nil {
-		return nil, InputTypeNone, ErrMalformedInput
+func DecodeInspect(payload []byte) (interface{}, InputType, error) {
+	req, err := DecodeInspectRequest(payload)
+	if err != nil {
+		return nil, InputTypeNone, err
 	}
 
 	switch req.Method {
@@ -365,69 +373,17 @@ func DecodeERC1155BatchDeposit(payload []byte) (*ERC1155BatchDeposit, error) {
 		Sender: common.BytesToAddress(payload[20:40]),
 	}
 
-	offset := 40
-	idsOffset := new(big.Int).SetBytes(payload[offset:offset+32]).Uint64() + 40
-	offset += 32
-
-	if len(payload) < int(idsOffset)+32 {
+	// The arguments after token and sender are ABI-encoded, with offsets
+	// relative to their start.
+	var ok bool
+	if deposit.TokenIDs, ok = decodeUint256Array(payload, 40, 40); !ok {
 		return nil, ErrMalformedInput
 	}
-
-	idsCount := new(big.Int).SetBytes(payload[idsOffset : idsOffset+32]).Uint64()
-	idsStart := idsOffset + 32
-
-	if len(payload) < int(idsStart)+int(idsCount)*32 {
+	if deposit.Amounts, ok = decodeUint256Array(payload, 72, 40); !ok {
 		return nil, ErrMalformedInput
 	}
-
-	deposit.TokenIDs = make([]*big.Int, idsCount)
-	for i := uint64(0); i < idsCount; i++ {
-		start := idsStart + i*32
-		deposit.TokenIDs[i] = new(big.Int).SetBytes(payload[start : start+32])
-	}
-
-	amountsOffset := new(big.Int).SetBytes(payload[offset:offset+32]).Uint64() + 40
-	offset += 32
-
-	if len(payload) < int(amountsOffset)+32 {
-		return nil, ErrMalformedInput
-	}
-
-	amountsCount := new(big.Int).SetBytes(payload[amountsOffset : amountsOffset+32]).Uint64()
-	amountsStart := amountsOffset + 32
-
-	if len(payload) < int(amountsStart)+int(amountsCount)*32 {
-		return nil, ErrMalformedInput
-	}
-
-	deposit.Amounts = make([]*big.Int, amountsCount)
-	for i := uint64(0); i < amountsCount; i++ {
-		start := amountsStart + i*32
-		deposit.Amounts[i] = new(big.Int).SetBytes(payload[start : start+32])
-	}
-
-	baseLayerDataOffset := new(big.Int).SetBytes(payload[offset : offset+32]).Uint64()
-	offset += 32
-
-	if len(payload) >= int(baseLayerDataOffset)+32 {
-		baseLayerDataLen := new(big.Int).SetBytes(payload[baseLayerDataOffset : baseLayerDataOffset+32]).Uint64()
-		baseLayerDataStart := baseLayerDataOffset + 32
-		if len(payload) >= int(baseLayerDataStart)+int(baseLayerDataLen) {
-			deposit.BaseLayerData = make([]byte, baseLayerDataLen)
-			copy(deposit.BaseLayerData, payload[baseLayerDataStart:baseLayerDataStart+baseLayerDataLen])
-		}
-	}
-
-	execLayerDataOffset := new(big.Int).SetBytes(payload[offset : offset+32]).Uint64()
-
-	if len(payload) >= int(execLayerDataOffset)+32 {
-		execLayerDataLen := new(big.Int).SetBytes(payload[execLayerDataOffset : execLayerDataOffset+32]).Uint64()
-		execLayerDataStart := execLayerDataOffset + 32
-		if len(payload) >= int(execLayerDataStart)+int(execLayerDataLen) {
-			deposit.ExecLayerData = make([]byte, execLayerDataLen)
-			copy(deposit.ExecLayerData, payload[execLayerDataStart:execLayerDataStart+execLayerDataLen])
-		}
-	}
+	deposit.BaseLayerData, _ = decodeBytes(payload, 104, 40)
+	deposit.ExecLayerData, _ = decodeBytes(payload, 136, 40)
 
 	return deposit, nil
 }
@@ -538,46 +494,14 @@ func DecodeERC1155BatchWithdrawal(payload []byte) (*ERC1155BatchWithdrawal, erro
 		Token: common.BytesToAddress(payload[16:36]),
 	}
 
-	offset := 36
-	idsOffset := new(big.Int).SetBytes(payload[offset:offset+32]).Uint64() + 4
-	offset += 32
-
-	if len(payload) < int(idsOffset)+32 {
+	var ok bool
+	if withdrawal.TokenIDs, ok = decodeUint256Array(payload, 36, 4); !ok {
 		return nil, ErrMalformedInput
 	}
-
-	idsCount := new(big.Int).SetBytes(payload[idsOffset : idsOffset+32]).Uint64()
-	idsStart := idsOffset + 32
-
-	if len(payload) < int(idsStart)+int(idsCount)*32 {
+	if withdrawal.Amounts, ok = decodeUint256Array(payload, 68, 4); !ok {
 		return nil, ErrMalformedInput
 	}
-
-	withdrawal.TokenIDs = make([]*big.Int, idsCount)
-	for i := uint64(0); i < idsCount; i++ {
-		start := idsStart + i*32
-		withdrawal.TokenIDs[i] = new(big.Int).SetBytes(payload[start : start+32])
-	}
-
-	amountsOffset := new(big.Int).SetBytes(payload[offset:offset+32]).Uint64() + 4
-	offset += 32
-
-	if len(payload) < int(amountsOffset)+32 {
-		return nil, ErrMalformedInput
-	}
-
-	amountsCount := new(big.Int).SetBytes(payload[amountsOffset : amountsOffset+32]).Uint64()
-	amountsStart := amountsOffset + 32
-
-	if len(payload) < int(amountsStart)+int(amountsCount)*32 {
-		return nil, ErrMalformedInput
-	}
-
-	withdrawal.Amounts = make([]*big.Int, amountsCount)
-	for i := uint64(0); i < amountsCount; i++ {
-		start := amountsStart + i*32
-		withdrawal.Amounts[i] = new(big.Int).SetBytes(payload[start : start+32])
-	}
+	withdrawal.ExecLayerData = decodeTrailingBytes(payload, 100)
 
 	return withdrawal, nil
 }
@@ -693,47 +617,94 @@ func DecodeERC1155BatchTransfer(payload []byte) (*ERC1155BatchTransfer, error) {
 		Receiver: common.BytesToHash(payload[36:68]),
 	}
 
-	offset := 68
-	idsOffset := new(big.Int).SetBytes(payload[offset:offset+32]).Uint64() + 4
-	offset += 32
-
-	if len(payload) < int(idsOffset)+32 {
+	var ok bool
+	if transfer.TokenIDs, ok = decodeUint256Array(payload, 68, 4); !ok {
 		return nil, ErrMalformedInput
 	}
-
-	idsCount := new(big.Int).SetBytes(payload[idsOffset : idsOffset+32]).Uint64()
-	idsStart := idsOffset + 32
-
-	if len(payload) < int(idsStart)+int(idsCount)*32 {
+	if transfer.Amounts, ok = decodeUint256Array(payload, 100, 4); !ok {
 		return nil, ErrMalformedInput
 	}
+	transfer.ExecLayerData = decodeTrailingBytes(payload, 132)
 
-	transfer.TokenIDs = make([]*big.Int, idsCount)
-	for i := uint64(0); i < idsCount; i++ {
-		start := idsStart + i*32
-		transfer.TokenIDs[i] = new(big.Int).SetBytes(payload[start : start+32])
-	}
+	return transfer, nil
+}
+
+// decodeTrailingBytes decodes the ABI bytes argument of a ledger batch input
+// whose offset word is at pos, returning nil when it is missing or out of
+// bounds, as in payloads built without execution layer data.
+func decodeTrailingBytes(payload []byte, pos uint64) []byte {
+	data, _ := decodeBytes(payload, pos, 4)
+	return data
+}
 
-	amountsOffset := new(big.Int).SetBytes(payload[offset:offset+32]).Uint64() + 4
+// The helpers below read ABI-encoded arguments whose offset word is at pos,
+// with offsets relative to base. Offsets and lengths come from the input, so
+// every bound is checked in uint64 against the payload length before slicing.
 
-	if len(payload) < int(amountsOffset)+32 {
-		return nil, ErrMalformedInput
+// abiUint reads the 32-byte word at pos, reporting false when the payload
+// ends before it or its value does not fit in a uint64.
+func abiUint(payload []byte, pos uint64) (uint64, bool) {
+	if pos > uint64(len(payload)) || uint64(len(payload))-pos < 32 {
+		return 0, false
 	}
+	word := payload[pos : pos+32]
+	for _, b := range word[:24] {
+		if b != 0 {
+			return 0, false
+		}
+	}
+	return binary.BigEndian.Uint64(word[24:]), true
+}
 
-	amountsCount := new(big.Int).SetBytes(payload[amountsOffset : amountsOffset+32]).Uint64()
-	amountsStart := amountsOffset + 32
+// abiTail returns the position of the dynamic argument whose offset word is
+// at pos.
+func abiTail(payload []byte, pos, base uint64) (uint64, bool) {
+	offset, ok := abiUint(payload, pos)
+	if !ok || offset > math.MaxUint64-base {
+		return 0, false
+	}
+	return base + offset, true
+}
 
-	if len(payload) < int(amountsStart)+int(amountsCount)*32 {
-		return nil, ErrMalformedInput
+func decodeUint256Array(payload []byte, pos, base uint64) ([]*big.Int, bool) {
+	start, ok := abiTail(payload, pos, base)
+	if !ok {
+		return nil, false
+	}
+	count, ok := abiUint(payload, start)
+	if !ok {
+		return nil, false
+	}
+	start += 32
+	if count > (uint64(len(payload))-start)/32 {
+		return nil, false
 	}
 
-	transfer.Amounts = make([]*big.Int, amountsCount)
-	for i := uint64(0); i < amountsCount; i++ {
-		start := amountsStart + i*32
-		transfer.Amounts[i] = new(big.Int).SetBytes(payload[start : start+32])
+	values := make([]*big.Int, count)
+	for i := range values {
+		at := start + uint64(i)*32
+		values[i] = new(big.Int).SetBytes(payload[at : at+32])
 	}
+	return values, true
+}
 
-	return transfer, nil
+func decodeBytes(payload []byte, pos, base uint64) ([]byte, bool) {
+	start, ok := abiTail(payload, pos, base)
+	if !ok {
+		return nil, false
+	}
+	n, ok := abiUint(payload, start)
+	if !ok {
+		return nil, false
+	}
+	start += 32
+	if n > uint64(len(payload))-start {
+		return nil, false
+	}
+
+	data := make([]byte, n)
+	copy(data, payload[start:start+n])
+	return data, true
 }
 
 func DecodeBalanceQuery(payload []byte) (*BalanceQuery, InputType, error) {
@@ -0,0 +1,223 @@
+package parser_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+)
+
+var (
+	token    = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	sender   = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	receiver = common.HexToHash("0x00000000000000000000000000000000000000cc")
+	data     = []byte("exec layer data")
+)
+
+func n(x int64) *big.Int {
+	return big.NewInt(x)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		inputType parser.InputType
+		input     interface{}
+	}{
+		{"ether deposit", parser.InputTypeEtherDeposit, &parser.EtherDeposit{Sender: sender, Amount: n(1), ExecLayerData: data}},
+		{"erc20 deposit", parser.InputTypeERC20Deposit, &parser.ERC20Deposit{Token: token, Sender: sender, Amount: n(2), ExecLayerData: data}},
+		{"erc721 deposit", parser.InputTypeERC721Deposit, &parser.ERC721Deposit{Token: token, Sender: sender, TokenID: n(3), ExecLayerData: data}},
+		{"erc1155 single deposit", parser.InputTypeERC1155SingleDeposit, &parser.ERC1155SingleDeposit{Token: token, Sender: sender, TokenID: n(4), Amount: n(5), ExecLayerData: data}},
+		{"erc1155 batch deposit", parser.InputTypeERC1155BatchDeposit, &parser.ERC1155BatchDeposit{Token: token, Sender: sender, TokenIDs: []*big.Int{n(6), n(7)}, Amounts: []*big.Int{n(8), n(9)}, BaseLayerData: []byte("base layer data"), ExecLayerData: data}},
+		{"ether withdrawal", parser.InputTypeEtherWithdrawal, &parser.EtherWithdrawal{Amount: n(10), ExecLayerData: data}},
+		{"erc20 withdrawal", parser.InputTypeERC20Withdrawal, &parser.ERC20Withdrawal{Token: token, Amount: n(11), ExecLayerData: data}},
+		{"erc721 withdrawal", parser.InputTypeERC721Withdrawal, &parser.ERC721Withdrawal{Token: token, TokenID: n(12), ExecLayerData: data}},
+		{"erc1155 single withdrawal", parser.InputTypeERC1155SingleWithdrawal, &parser.ERC1155SingleWithdrawal{Token: token, TokenID: n(13), Amount: n(14), ExecLayerData: data}},
+		{"erc1155 batch withdrawal", parser.InputTypeERC1155BatchWithdrawal, &parser.ERC1155BatchWithdrawal{Token: token, TokenIDs: []*big.Int{n(15)}, Amounts: []*big.Int{n(16)}, ExecLayerData: data}},
+		{"ether transfer", parser.InputTypeEtherTransfer, &parser.EtherTransfer{Receiver: receiver, Amount: n(17), ExecLayerData: data}},
+		{"erc20 transfer", parser.InputTypeERC20Transfer, &parser.ERC20Transfer{Token: token, Receiver: receiver, Amount: n(18), ExecLayerData: data}},
+		{"erc721 transfer", parser.InputTypeERC721Transfer, &parser.ERC721Transfer{Token: token, Receiver: receiver, TokenID: n(19), ExecLayerData: data}},
+		{"erc1155 single transfer", parser.InputTypeERC1155SingleTransfer, &parser.ERC1155SingleTransfer{Token: token, Receiver: receiver, TokenID: n(20), Amount: n(21), ExecLayerData: data}},
+		{"erc1155 batch transfer", parser.InputTypeERC1155BatchTransfer, &parser.ERC1155BatchTransfer{Token: token, Receiver: receiver, TokenIDs: []*big.Int{n(22), n(23), n(24)}, Amounts: []*big.Int{n(25), n(26), n(27)}, ExecLayerData: data}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := parser.EncodeAdvance(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parser.DecodeAdvance(tc.inputType, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.input) {
+				t.Fatalf("got %+v, want %+v", got, tc.input)
+			}
+		})
+	}
+
+	if _, err := parser.EncodeAdvance(struct{}{}); err != parser.ErrUnknownInputType {
+		t.Fatalf("expected %v, got %v", parser.ErrUnknownInputType, err)
+	}
+}
+
+func TestInspectRoundTrip(t *testing.T) {
+	account := common.HexToHash("0x01")
+
+	balance, err := parser.EncodeBalanceRequest(account, token, n(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, inputType, err := parser.DecodeInspect(balance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &parser.BalanceQuery{Account: account, Token: token, TokenID: n(7)}
+	if inputType != parser.InputTypeBalanceAccountTokenAddressID || !reflect.DeepEqual(query, want) {
+		t.Fatalf("got %+v (%v), want %+v", query, inputType, want)
+	}
+
+	supply, err := parser.EncodeSupplyRequest(common.Address{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, inputType, err := parser.DecodeInspect(supply); err != nil || inputType != parser.InputTypeSupply {
+		t.Fatalf("expected a supply query without token, got %v, %v", inputType, err)
+	}
+
+	encoded, err := parser.EncodeBalanceQuery(want, parser.InputTypeBalanceAccountTokenAddressID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := parser.DecodeBalanceQuery(encoded); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v, want %+v", got, err, want)
+	}
+	if _, err := parser.EncodeSupplyQuery(&parser.SupplyQuery{}, parser.InputTypeBalance); err != parser.ErrIncompatibleInput {
+		t.Fatalf("expected %v, got %v", parser.ErrIncompatibleInput, err)
+	}
+}
+
+func TestDecodeBatchWithoutExecLayerData(t *testing.T) {
+	addressType, _ := abi.NewType("address", "", nil)
+	uint256ArrayType, _ := abi.NewType("uint256[]", "", nil)
+	args, err := abi.Arguments{{Type: addressType}, {Type: uint256ArrayType}, {Type: uint256ArrayType}}.Pack(token, []*big.Int{n(1)}, []*big.Int{n(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := append(binary.BigEndian.AppendUint32(nil, parser.SelectorWithdrawERC1155Batch), args...)
+
+	// Payloads built without the trailing bytes argument are still accepted.
+	w, err := parser.DecodeERC1155BatchWithdrawal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.ExecLayerData != nil {
+		t.Fatalf("expected no exec layer data, got %x", w.ExecLayerData)
+	}
+}
+
+func TestInputTypeNames(t *testing.T) {
+	for typ := parser.InputTypeNone; typ <= parser.InputTypeSupplyTokenAddressID; typ++ {
+		got, err := parser.ParseInputType(typ.String())
+		if err != nil || got != typ {
+			t.Fatalf("%s: got %v, %v", typ, got, err)
+		}
+	}
+	if _, err := parser.ParseInputType("Deposit"); !errors.Is(err, parser.ErrUnknownInputType) {
+		t.Fatalf("expected %v, got %v", parser.ErrUnknownInputType, err)
+	}
+}
+
+// withWord returns payload with the 32-byte word at pos replaced by value.
+func withWord(payload []byte, pos int, value []byte) []byte {
+	out := bytes.Clone(payload)
+	copy(out[pos:pos+32], common.LeftPadBytes(value, 32))
+	return out
+}
+
+func TestDecodeMalformedBatches(t *testing.T) {
+	huge := common.FromHex("0x7ffffffffffffffc")
+	wide := common.FromHex("0x010000000000000000")
+
+	withdrawal, err := parser.EncodeERC1155BatchWithdrawal(&parser.ERC1155BatchWithdrawal{
+		Token:         token,
+		TokenIDs:      []*big.Int{n(1)},
+		Amounts:       []*big.Int{n(2)},
+		ExecLayerData: data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer, err := parser.EncodeERC1155BatchTransfer(&parser.ERC1155BatchTransfer{
+		Token:    token,
+		Receiver: receiver,
+		TokenIDs: []*big.Int{n(1)},
+		Amounts:  []*big.Int{n(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit, err := parser.EncodeERC1155BatchDeposit(&parser.ERC1155BatchDeposit{
+		Token:    token,
+		Sender:   sender,
+		TokenIDs: []*big.Int{n(1)},
+		Amounts:  []*big.Int{n(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		inputType parser.InputType
+		payload   []byte
+	}{
+		{"withdrawal ids offset", parser.InputTypeERC1155BatchWithdrawal, withWord(withdrawal, 36, huge)},
+		{"withdrawal ids offset above 64 bits", parser.InputTypeERC1155BatchWithdrawal, withWord(withdrawal, 36, wide)},
+		{"withdrawal amounts offset", parser.InputTypeERC1155BatchWithdrawal, withWord(withdrawal, 68, huge)},
+		{"withdrawal ids count", parser.InputTypeERC1155BatchWithdrawal, withWord(withdrawal, 4+4*32, huge)},
+		{"withdrawal truncated", parser.InputTypeERC1155BatchWithdrawal, withdrawal[:4+2*32]},
+		{"transfer ids offset", parser.InputTypeERC1155BatchTransfer, withWord(transfer, 68, huge)},
+		{"transfer amounts count", parser.InputTypeERC1155BatchTransfer, withWord(transfer, 4+7*32, huge)},
+		{"deposit ids offset", parser.InputTypeERC1155BatchDeposit, withWord(deposit, 40, huge)},
+		{"deposit amounts offset", parser.InputTypeERC1155BatchDeposit, withWord(deposit, 72, huge)},
+		{"deposit truncated", parser.InputTypeERC1155BatchDeposit, deposit[:80]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parser.DecodeAdvance(tc.inputType, tc.payload); !errors.Is(err, parser.ErrMalformedInput) {
+				t.Fatalf("expected %v, got %v", parser.ErrMalformedInput, err)
+			}
+		})
+	}
+}
+
+func TestDecodeOutOfBoundsExecLayerData(t *testing.T) {
+	withdrawal, err := parser.EncodeERC1155BatchWithdrawal(&parser.ERC1155BatchWithdrawal{
+		Token:         token,
+		TokenIDs:      []*big.Int{n(1)},
+		Amounts:       []*big.Int{n(2)},
+		ExecLayerData: data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, payload := range [][]byte{
+		withWord(withdrawal, 100, common.FromHex("0x7ffffffffffffffc")),
+		withWord(withdrawal, 100, common.FromHex("0xfffffffffffffffe")),
+		withWord(withdrawal, 4+8*32, common.FromHex("0x7fffffffffffffff")),
+	} {
+		w, err := parser.DecodeERC1155BatchWithdrawal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.ExecLayerData != nil {
+			t.Fatalf("expected no exec layer data, got %x", w.ExecLayerData)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"fmt"
 	"math/big"
+	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -27,6 +29,13 @@ type DelegateCallVoucher struct {
 	Payload     []byte
 }
 
+// Inspect
+
+type InspectRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
 // Ledger
 
 type InputType int
@@ -58,6 +67,52 @@ const (
 	InputTypeSupplyTokenAddressID
 )
 
+var inputTypeNames = [...]string{
+	InputTypeNone:                         "None",
+	InputTypeAuto:                         "Auto",
+	InputTypeEtherDeposit:                 "EtherDeposit",
+	InputTypeERC20Deposit:                 "ERC20Deposit",
+	InputTypeERC721Deposit:                "ERC721Deposit",
+	InputTypeERC1155SingleDeposit:         "ERC1155SingleDeposit",
+	InputTypeERC1155BatchDeposit:          "ERC1155BatchDeposit",
+	InputTypeEtherWithdrawal:              "EtherWithdrawal",
+	InputTypeERC20Withdrawal:              "ERC20Withdrawal",
+	InputTypeERC721Withdrawal:             "ERC721Withdrawal",
+	InputTypeERC1155SingleWithdrawal:      "ERC1155SingleWithdrawal",
+	InputTypeERC1155BatchWithdrawal:       "ERC1155BatchWithdrawal",
+	InputTypeEtherTransfer:                "EtherTransfer",
+	InputTypeERC20Transfer:                "ERC20Transfer",
+	InputTypeERC721Transfer:               "ERC721Transfer",
+	InputTypeERC1155SingleTransfer:        "ERC1155SingleTransfer",
+	InputTypeERC1155BatchTransfer:         "ERC1155BatchTransfer",
+	InputTypeBalance:                      "Balance",
+	InputTypeBalanceAccount:               "BalanceAccount",
+	InputTypeBalanceAccountTokenAddress:   "BalanceAccountTokenAddress",
+	InputTypeBalanceAccountTokenAddressID: "BalanceAccountTokenAddressID",
+	InputTypeSupply:                       "Supply",
+	InputTypeSupplyTokenAddress:           "SupplyTokenAddress",
+	InputTypeSupplyTokenAddressID:         "SupplyTokenAddressID",
+}
+
+// String returns the name of the input type without the InputType prefix,
+// e.g. "ERC20Deposit".
+func (t InputType) String() string {
+	if t < 0 || int(t) >= len(inputTypeNames) {
+		return "InputType(" + strconv.Itoa(int(t)) + ")"
+	}
+	return inputTypeNames[t]
+}
+
+// ParseInputType returns the input type named name, as returned by String.
+func ParseInputType(name string) (InputType, error) {
+	for t, n := range inputTypeNames {
+		if n == name {
+			return InputType(t), nil
+		}
+	}
+	return InputTypeNone, fmt.Errorf("%w: %q", ErrUnknownInputType, name)
+}
+
 type EtherDeposit struct {
 	Sender        common.Address
 	Amount        *big.Int
@@ -0,0 +1,74 @@
+package rollup
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Emitter is the output side of an Interface.
+type Emitter interface {
+	EmitVoucher(address common.Address, value *big.Int, data []byte) (uint64, error)
+	EmitDelegateCallVoucher(address common.Address, data []byte) (uint64, error)
+	EmitNotice(payload []byte) (uint64, error)
+	EmitReport(payload []byte) error
+}
+
+// Capture is an Emitter that records outputs instead of emitting them, for
+// running handlers whose outputs must not reach the machine. Vouchers,
+// delegate call vouchers and notices share a single index sequence, as they
+// do in libcmt.
+type Capture struct {
+	Vouchers             []Voucher
+	DelegateCallVouchers []DelegateCallVoucher
+	Notices              []Notice
+	Reports              []Report
+
+	outputs uint64
+}
+
+func (c *Capture) EmitVoucher(address common.Address, value *big.Int, data []byte) (uint64, error) {
+	v := Voucher{
+		Index:       c.nextIndex(),
+		Destination: address,
+		Value:       voucherValue(value),
+		Payload:     append([]byte(nil), data...),
+	}
+	c.Vouchers = append(c.Vouchers, v)
+	return v.Index, nil
+}
+
+func (c *Capture) EmitDelegateCallVoucher(address common.Address, data []byte) (uint64, error) {
+	v := DelegateCallVoucher{
+		Index:       c.nextIndex(),
+		Destination: address,
+		Payload:     append([]byte(nil), data...),
+	}
+	c.DelegateCallVouchers = append(c.DelegateCallVouchers, v)
+	return v.Index, nil
+}
+
+func (c *Capture) EmitNotice(payload []byte) (uint64, error) {
+	n := Notice{Index: c.nextIndex(), Payload: append([]byte(nil), payload...)}
+	c.Notices = append(c.Notices, n)
+	return n.Index, nil
+}
+
+func (c *Capture) EmitReport(payload []byte) error {
+	c.Reports = append(c.Reports, Report{Payload: append([]byte(nil), payload...)})
+	return nil
+}
+
+func (c *Capture) nextIndex() uint64 {
+	index := c.outputs
+	c.outputs++
+	return index
+}
+
+// voucherValue copies value, taking nil as zero as libcmt does.
+func voucherValue(value *big.Int) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(value)
+}
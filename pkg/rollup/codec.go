@@ -0,0 +1,177 @@
+package rollup
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Machine formats of inputs and outputs, as ABI-encoded calls to the
+// functions of the Cartesi Rollups Inputs and Outputs interfaces.
+
+var (
+	uint256Type, _ = abi.NewType("uint256", "", nil)
+	addressType, _ = abi.NewType("address", "", nil)
+	bytesType, _   = abi.NewType("bytes", "", nil)
+
+	evmAdvanceArguments = abi.Arguments{
+		{Type: uint256Type}, // chainId
+		{Type: addressType}, // appContract
+		{Type: addressType}, // msgSender
+		{Type: uint256Type}, // blockNumber
+		{Type: uint256Type}, // blockTimestamp
+		{Type: uint256Type}, // prevRandao
+		{Type: uint256Type}, // index
+		{Type: bytesType},   // payload
+	}
+	noticeArguments              = abi.Arguments{{Type: bytesType}}
+	voucherArguments             = abi.Arguments{{Type: addressType}, {Type: uint256Type}, {Type: bytesType}}
+	delegateCallVoucherArguments = abi.Arguments{{Type: addressType}, {Type: bytesType}}
+
+	EvmAdvanceSelector          = selectorOf("EvmAdvance(uint256,address,address,uint256,uint256,uint256,uint256,bytes)")
+	NoticeSelector              = selectorOf("Notice(bytes)")
+	VoucherSelector             = selectorOf("Voucher(address,uint256,bytes)")
+	DelegateCallVoucherSelector = selectorOf("DelegateCallVoucher(address,bytes)")
+)
+
+func selectorOf(signature string) [4]byte {
+	var s [4]byte
+	copy(s[:], crypto.Keccak256([]byte(signature)))
+	return s
+}
+
+// EncodeAdvance encodes advance as the EvmAdvance input the machine reads.
+func EncodeAdvance(advance *Advance) ([]byte, error) {
+	m := advance.Metadata
+	data, err := evmAdvanceArguments.Pack(
+		new(big.Int).SetUint64(m.ChainID),
+		m.AppContract,
+		m.MsgSender,
+		new(big.Int).SetUint64(m.BlockNumber),
+		new(big.Int).SetUint64(m.BlockTimestamp),
+		new(big.Int).SetBytes(m.PrevRandao.Bytes()),
+		new(big.Int).SetUint64(m.Index),
+		payloadOrEmpty(advance.Payload),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return append(EvmAdvanceSelector[:], data...), nil
+}
+
+// DecodeAdvance decodes an EvmAdvance input.
+func DecodeAdvance(data []byte) (*Advance, error) {
+	values, err := unpackCall(EvmAdvanceSelector, evmAdvanceArguments, data)
+	if err != nil {
+		return nil, fmt.Errorf("EvmAdvance: %w", err)
+	}
+
+	var fields [4]uint64
+	for i, pos := range []int{0, 3, 4, 6} {
+		n := values[pos].(*big.Int)
+		if !n.IsUint64() {
+			return nil, fmt.Errorf("%w: EvmAdvance: argument %d overflows uint64", ErrInvalidArgument, pos)
+		}
+		fields[i] = n.Uint64()
+	}
+
+	return &Advance{
+		Metadata: Metadata{
+			ChainID:        fields[0],
+			AppContract:    values[1].(common.Address),
+			MsgSender:      values[2].(common.Address),
+			BlockNumber:    fields[1],
+			BlockTimestamp: fields[2],
+			PrevRandao:     common.BigToHash(values[5].(*big.Int)),
+			Index:          fields[3],
+		},
+		Payload: values[7].([]byte),
+	}, nil
+}
+
+// EncodeNotice encodes n as the Notice output the machine emits.
+func EncodeNotice(n Notice) []byte {
+	data, _ := noticeArguments.Pack(payloadOrEmpty(n.Payload))
+	return append(NoticeSelector[:], data...)
+}
+
+// EncodeVoucher encodes v as the Voucher output the machine emits. A nil
+// value encodes as zero.
+func EncodeVoucher(v Voucher) []byte {
+	value := v.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	data, _ := voucherArguments.Pack(v.Destination, value, payloadOrEmpty(v.Payload))
+	return append(VoucherSelector[:], data...)
+}
+
+// EncodeDelegateCallVoucher encodes v as the DelegateCallVoucher output the
+// machine emits.
+func EncodeDelegateCallVoucher(v DelegateCallVoucher) []byte {
+	data, _ := delegateCallVoucherArguments.Pack(v.Destination, payloadOrEmpty(v.Payload))
+	return append(DelegateCallVoucherSelector[:], data...)
+}
+
+// DecodeOutput decodes an output into a Notice, Voucher or
+// DelegateCallVoucher, leaving its Index unset.
+func DecodeOutput(data []byte) (interface{}, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: output shorter than a selector", ErrInvalidArgument)
+	}
+
+	switch {
+	case bytes.Equal(data[:4], NoticeSelector[:]):
+		values, err := unpackCall(NoticeSelector, noticeArguments, data)
+		if err != nil {
+			return nil, fmt.Errorf("Notice: %w", err)
+		}
+		return Notice{Payload: values[0].([]byte)}, nil
+
+	case bytes.Equal(data[:4], VoucherSelector[:]):
+		values, err := unpackCall(VoucherSelector, voucherArguments, data)
+		if err != nil {
+			return nil, fmt.Errorf("Voucher: %w", err)
+		}
+		return Voucher{
+			Destination: values[0].(common.Address),
+			Value:       values[1].(*big.Int),
+			Payload:     values[2].([]byte),
+		}, nil
+
+	case bytes.Equal(data[:4], DelegateCallVoucherSelector[:]):
+		values, err := unpackCall(DelegateCallVoucherSelector, delegateCallVoucherArguments, data)
+		if err != nil {
+			return nil, fmt.Errorf("DelegateCallVoucher: %w", err)
+		}
+		return DelegateCallVoucher{
+			Destination: values[0].(common.Address),
+			Payload:     values[1].([]byte),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown output selector %x", ErrInvalidArgument, data[:4])
+	}
+}
+
+func unpackCall(selector [4]byte, arguments abi.Arguments, data []byte) ([]interface{}, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], selector[:]) {
+		return nil, fmt.Errorf("%w: selector mismatch", ErrInvalidArgument)
+	}
+	values, err := arguments.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	return values, nil
+}
+
+func payloadOrEmpty(payload []byte) []byte {
+	if payload == nil {
+		return []byte{}
+	}
+	return payload
+}
@@ -0,0 +1,146 @@
+package rollup_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+var (
+	app    = common.HexToAddress("0xab7528bb862fB57E8A2BCd567a2e929a0Be56a5e")
+	sender = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+)
+
+// abiHex joins 4-byte selectors and 32-byte words given in hex, left-padding
+// the words that are shorter, as the encoder.ts functions lay them out.
+func abiHex(t *testing.T, selector string, words ...string) []byte {
+	t.Helper()
+
+	var b strings.Builder
+	b.WriteString(selector)
+	for _, w := range words {
+		if len(w) > 64 {
+			t.Fatalf("word %s is longer than 32 bytes", w)
+		}
+		b.WriteString(strings.Repeat("0", 64-len(w)) + w)
+	}
+	return common.FromHex(b.String())
+}
+
+// payloadWord right-pads data to a 32-byte word.
+func payloadWord(data string) string {
+	return data + strings.Repeat("0", 64-len(data))
+}
+
+func TestEncodeAdvanceVector(t *testing.T) {
+	advance := &rollup.Advance{
+		Metadata: rollup.Metadata{
+			ChainID:        31337,
+			AppContract:    app,
+			MsgSender:      sender,
+			BlockNumber:    0x10,
+			BlockTimestamp: 0x20,
+			PrevRandao:     common.HexToHash("0x30"),
+			Index:          0x40,
+		},
+		Payload: common.FromHex("0xdeadbeef"),
+	}
+	// encodeAdvanceInput({chainId: 31337n, appContract, msgSender,
+	// blockNumber: 16n, blockTimestamp: 32n, prevRandao: 48n, index: 64n,
+	// payload: "0xdeadbeef"})
+	want := abiHex(t, "0x415bf363",
+		"7a69",
+		"ab7528bb862fb57e8a2bcd567a2e929a0be56a5e",
+		"f39fd6e51aad88f6f4ce6ab8827279cfffb92266",
+		"10", "20", "30", "40",
+		"100", // payload offset
+		"4", payloadWord("deadbeef"),
+	)
+
+	got, err := rollup.EncodeAdvance(advance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got  %x\nwant %x", got, want)
+	}
+
+	decoded, err := rollup.DecodeAdvance(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, advance) {
+		t.Fatalf("got %+v, want %+v", decoded, advance)
+	}
+}
+
+func TestDecodeAdvanceRejectsMalformedInputs(t *testing.T) {
+	valid, err := rollup.EncodeAdvance(&rollup.Advance{Payload: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overflow := bytes.Clone(valid)
+	overflow[4] = 1 // chainId above 64 bits
+
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"selector":  append([]byte{0, 0, 0, 0}, valid[4:]...),
+		"truncated": valid[:100],
+		"overflow":  overflow,
+	} {
+		if _, err := rollup.DecodeAdvance(data); !errors.Is(err, rollup.ErrInvalidArgument) {
+			t.Errorf("%s: expected %v, got %v", name, rollup.ErrInvalidArgument, err)
+		}
+	}
+}
+
+func TestEncodeOutputVectors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output interface{}
+		got    []byte
+		want   []byte
+	}{
+		{
+			// encodeNoticeOutput({payload: "0xdeadbeef"})
+			name:   "notice",
+			output: rollup.Notice{Payload: common.FromHex("0xdeadbeef")},
+			got:    rollup.EncodeNotice(rollup.Notice{Payload: common.FromHex("0xdeadbeef")}),
+			want:   abiHex(t, "0xc258d6e5", "20", "4", payloadWord("deadbeef")),
+		},
+		{
+			// encodeVoucherOutput({destination: sender, value: 1000n,
+			// payload: "0xcafe"})
+			name:   "voucher",
+			output: rollup.Voucher{Destination: sender, Value: big.NewInt(1000), Payload: common.FromHex("0xcafe")},
+			got:    rollup.EncodeVoucher(rollup.Voucher{Destination: sender, Value: big.NewInt(1000), Payload: common.FromHex("0xcafe")}),
+			want:   abiHex(t, "0x237a816f", "f39fd6e51aad88f6f4ce6ab8827279cfffb92266", "3e8", "60", "2", payloadWord("cafe")),
+		},
+		{
+			// encodeDelegateCallVoucherOutput({destination: app, payload: "0x"})
+			name:   "delegate call voucher",
+			output: rollup.DelegateCallVoucher{Destination: app, Payload: []byte{}},
+			got:    rollup.EncodeDelegateCallVoucher(rollup.DelegateCallVoucher{Destination: app}),
+			want:   abiHex(t, "0x10321e8b", "ab7528bb862fb57e8a2bcd567a2e929a0be56a5e", "40", "0"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if !bytes.Equal(tc.got, tc.want) {
+				t.Fatalf("got  %x\nwant %x", tc.got, tc.want)
+			}
+			decoded, err := rollup.DecodeOutput(tc.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, tc.output) {
+				t.Fatalf("got %+v, want %+v", decoded, tc.output)
+			}
+		})
+	}
+}
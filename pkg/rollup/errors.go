@@ -1,6 +1,11 @@
 package rollup
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+)
 
 var (
 	ErrIOError         = errors.New("I/O error")
@@ -8,4 +13,61 @@ var (
 	ErrAlreadyFinished = errors.New("already finished")
 	ErrInvalidArgument = errors.New("invalid argument")
 	ErrNotInitialized  = errors.New("rollup not initialized")
+
+	// ErrOutputBufferFull is returned when an output, report or exception
+	// does not fit in the space left for the current request. Unlike an I/O
+	// error the machine is still usable, e.g. to report a shorter message.
+	ErrOutputBufferFull = errors.New("output buffer full")
+
+	// ErrPreimageNotFound is returned by Keccak256Preimage when the host
+	// answers with a non-zero response code.
+	ErrPreimageNotFound = errors.New("preimage not found")
+
+	// ErrEndOfInput is returned by the mock Finish once its input is closed
+	// and drained. It wraps io.EOF.
+	ErrEndOfInput = fmt.Errorf("end of input: %w", io.EOF)
 )
+
+// Error is the failure of a libcmt call. Errno is the error number the call
+// returned, negated, and Err the sentinel it maps to, so that
+//
+//	errors.Is(err, rollup.ErrOutputBufferFull)
+//	errors.Is(err, syscall.ENOBUFS)
+//
+// both hold for an output that does not fit.
+type Error struct {
+	Op    string
+	Errno syscall.Errno
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed: %v (errno %d)", e.Op, e.Err, int(e.Errno))
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Err, e.Errno}
+}
+
+// newError builds the error of a libcmt call that returned rc, a negative
+// errno, mapping the errno to one of the sentinel errors.
+func newError(op string, rc int) error {
+	errno := syscall.Errno(-rc)
+
+	var err error
+	switch errno {
+	case syscall.EINVAL:
+		err = ErrInvalidArgument
+	case syscall.EIO:
+		err = ErrIOError
+	case syscall.ENOBUFS:
+		err = ErrOutputBufferFull
+	case syscall.EALREADY:
+		err = ErrAlreadyFinished
+	case syscall.ENODEV, syscall.EBADF:
+		err = ErrNotInitialized
+	default:
+		err = ErrUnknown
+	}
+	return &Error{Op: op, Errno: errno, Err: err}
+}
@@ -0,0 +1,48 @@
+package rollup
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	var err error = &Error{Op: "cmt_rollup_emit_notice", Errno: syscall.ENOBUFS, Err: ErrOutputBufferFull}
+
+	if !errors.Is(err, ErrOutputBufferFull) || !errors.Is(err, syscall.ENOBUFS) {
+		t.Fatalf("expected %v to match its sentinel and errno", err)
+	}
+	if errors.Is(err, ErrIOError) || errors.Is(err, syscall.EIO) {
+		t.Fatalf("expected %v not to match other errors", err)
+	}
+
+	var rerr *Error
+	if !errors.As(err, &rerr) || rerr.Op != "cmt_rollup_emit_notice" {
+		t.Fatalf("expected errors.As to find the rollup error, got %v", rerr)
+	}
+	if got, want := err.Error(), fmt.Sprintf("cmt_rollup_emit_notice failed: output buffer full (errno %d)", int(syscall.ENOBUFS)); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	for _, tc := range []struct {
+		errno syscall.Errno
+		want  error
+	}{
+		{syscall.EINVAL, ErrInvalidArgument},
+		{syscall.EIO, ErrIOError},
+		{syscall.ENOBUFS, ErrOutputBufferFull},
+		{syscall.EALREADY, ErrAlreadyFinished},
+		{syscall.ENODEV, ErrNotInitialized},
+		{syscall.EBADF, ErrNotInitialized},
+		{syscall.EPERM, ErrUnknown},
+	} {
+		// libcmt returns the errno negated.
+		err := newError("cmt_rollup_finish", -int(tc.errno))
+		if !errors.Is(err, tc.want) || !errors.Is(err, tc.errno) {
+			t.Errorf("%v: expected %v to match %v and the errno", tc.errno, err, tc.want)
+		}
+	}
+}
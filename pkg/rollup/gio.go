@@ -0,0 +1,36 @@
+package rollup
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Keccak256PreimageDomain is the GIO domain in which the host answers a
+// keccak256 hash with its preimage.
+const Keccak256PreimageDomain uint16 = 0x2
+
+// GIORequester asks the host for data through generic I/O: id identifies
+// the data within domain and the host answers with a response code and data.
+type GIORequester interface {
+	GIORequest(domain uint16, id []byte) (code uint16, data []byte, err error)
+}
+
+// Keccak256Preimage asks the host for the data whose keccak256 hash is hash,
+// and checks the answer against it. A non-zero response code, the host not
+// knowing the preimage, fails with ErrPreimageNotFound.
+func Keccak256Preimage(r GIORequester, hash common.Hash) ([]byte, error) {
+	code, data, err := r.GIORequest(Keccak256PreimageDomain, hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("%w: %s: response code %d", ErrPreimageNotFound, hash.Hex(), code)
+	}
+	if got := crypto.Keccak256(data); !bytes.Equal(got, hash.Bytes()) {
+		return nil, fmt.Errorf("%w: preimage of %s: data hashing to %x", ErrInvalidArgument, hash.Hex(), got)
+	}
+	return data, nil
+}
@@ -0,0 +1,24 @@
+package rollup
+
+// Interface is the rollup as applications use it. The libcmt binding
+// implements it on the machine and the mock everywhere else, so code written
+// against it also accepts wrappers such as recorders or alternate drivers.
+type Interface interface {
+	Emitter
+	GIORequester
+	Progresser
+
+	// EmitException rejects the request being processed with payload.
+	EmitException(payload []byte) error
+
+	// Finish accepts or rejects the previous request and waits for the next
+	// one, returning its type and payload length.
+	Finish(accept bool) (RequestType, uint32, error)
+
+	// ReadAdvanceState and ReadInspectState read the request returned by the
+	// last Finish, according to its type.
+	ReadAdvanceState() (*Advance, error)
+	ReadInspectState() (*Inspect, error)
+}
+
+var _ Interface = (*Rollup)(nil)
@@ -0,0 +1,113 @@
+package rollup
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// OutputsTreeHeight is the height of the outputs Merkle tree, which holds
+// up to 2^63 outputs.
+const OutputsTreeHeight = 63
+
+// pristine holds the root of an empty subtree of each height, whose leaves
+// are zero.
+var pristine = func() [OutputsTreeHeight + 1]common.Hash {
+	var p [OutputsTreeHeight + 1]common.Hash
+	for i := 1; i <= OutputsTreeHeight; i++ {
+		p[i] = crypto.Keccak256Hash(p[i-1].Bytes(), p[i-1].Bytes())
+	}
+	return p
+}()
+
+// OutputHash is the leaf of output, an output encoded as the machine emits
+// it, e.g. by EncodeVoucher.
+func OutputHash(output []byte) common.Hash {
+	return crypto.Keccak256Hash(output)
+}
+
+// OutputsTree is the Merkle tree of the outputs of an application, whose
+// root the node claims on chain and against which vouchers are validated.
+// The zero value is an empty tree.
+type OutputsTree struct {
+	leaves []common.Hash
+}
+
+// Push appends output, encoded as the machine emits it, and returns its leaf.
+func (t *OutputsTree) Push(output []byte) common.Hash {
+	leaf := OutputHash(output)
+	t.leaves = append(t.leaves, leaf)
+	return leaf
+}
+
+// Len returns the number of outputs in the tree.
+func (t *OutputsTree) Len() uint64 {
+	return uint64(len(t.leaves))
+}
+
+// Root returns the root of the tree.
+func (t *OutputsTree) Root() common.Hash {
+	level := t.leaves
+	for height := 0; height < OutputsTreeHeight; height++ {
+		if len(level) == 0 {
+			return pristine[OutputsTreeHeight]
+		}
+		level = parents(level, height)
+	}
+	return level[0]
+}
+
+// Proof returns the siblings of the output at index from the leaf up, as in
+// the outputHashesSiblings of an output validity proof.
+func (t *OutputsTree) Proof(index uint64) ([]common.Hash, error) {
+	if index >= t.Len() {
+		return nil, fmt.Errorf("%w: output %d of %d", ErrInvalidArgument, index, t.Len())
+	}
+
+	siblings := make([]common.Hash, OutputsTreeHeight)
+	level := t.leaves
+	for height := 0; height < OutputsTreeHeight; height++ {
+		sibling := index ^ 1
+		if sibling < uint64(len(level)) {
+			siblings[height] = level[sibling]
+		} else {
+			siblings[height] = pristine[height]
+		}
+		level = parents(level, height)
+		index >>= 1
+	}
+	return siblings, nil
+}
+
+// parents hashes the nodes of a level, at height, in pairs, completing an odd
+// last pair with an empty subtree.
+func parents(level []common.Hash, height int) []common.Hash {
+	up := make([]common.Hash, (len(level)+1)/2)
+	for i := range up {
+		right := pristine[height]
+		if 2*i+1 < len(level) {
+			right = level[2*i+1]
+		}
+		up[i] = crypto.Keccak256Hash(level[2*i].Bytes(), right.Bytes())
+	}
+	return up
+}
+
+// VerifyOutput reports whether output is the output at index of the tree
+// with root, given the siblings returned by Proof.
+func VerifyOutput(root common.Hash, output []byte, index uint64, siblings []common.Hash) bool {
+	if len(siblings) != OutputsTreeHeight {
+		return false
+	}
+	node := OutputHash(output)
+	for _, sibling := range siblings {
+		if index&1 == 0 {
+			node = crypto.Keccak256Hash(node.Bytes(), sibling.Bytes())
+		} else {
+			node = crypto.Keccak256Hash(sibling.Bytes(), node.Bytes())
+		}
+		index >>= 1
+	}
+	return node == root
+}
@@ -0,0 +1,85 @@
+package rollup_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+func hashPair(left, right common.Hash) common.Hash {
+	return crypto.Keccak256Hash(left.Bytes(), right.Bytes())
+}
+
+// naiveRoot computes the root of leaves, at most 8, by hashing the complete
+// subtree of height 3 and lifting it with empty subtrees up to the root.
+func naiveRoot(leaves []common.Hash) common.Hash {
+	level := make([]common.Hash, 8)
+	copy(level, leaves)
+	for len(level) > 1 {
+		up := make([]common.Hash, len(level)/2)
+		for i := range up {
+			up[i] = hashPair(level[2*i], level[2*i+1])
+		}
+		level = up
+	}
+
+	root, empty := level[0], common.Hash{}
+	for height := 0; height < rollup.OutputsTreeHeight; height++ {
+		if height >= 3 {
+			root = hashPair(root, empty)
+		}
+		empty = hashPair(empty, empty)
+	}
+	return root
+}
+
+func TestOutputsTreeRoot(t *testing.T) {
+	tree := &rollup.OutputsTree{}
+	var leaves []common.Hash
+	for n := 0; n <= 8; n++ {
+		if got, want := tree.Root(), naiveRoot(leaves); got != want {
+			t.Fatalf("%d outputs: got root %s, want %s", n, got.Hex(), want.Hex())
+		}
+		leaves = append(leaves, tree.Push([]byte(fmt.Sprintf("output %d", n))))
+	}
+}
+
+func TestOutputsTreeProofs(t *testing.T) {
+	tree := &rollup.OutputsTree{}
+	var outputs [][]byte
+	for i := 0; i < 7; i++ {
+		output := rollup.EncodeNotice(rollup.Notice{Payload: []byte{byte(i)}})
+		outputs = append(outputs, output)
+		tree.Push(output)
+	}
+	root := tree.Root()
+
+	for i, output := range outputs {
+		index := uint64(i)
+		siblings, err := tree.Proof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rollup.VerifyOutput(root, output, index, siblings) {
+			t.Errorf("output %d: proof does not verify", i)
+		}
+		if rollup.VerifyOutput(root, outputs[(i+1)%len(outputs)], index, siblings) {
+			t.Errorf("output %d: proof verifies another output", i)
+		}
+		if rollup.VerifyOutput(root, output, index^1, siblings) {
+			t.Errorf("output %d: proof verifies at another index", i)
+		}
+		siblings[len(siblings)-1][0] ^= 1
+		if rollup.VerifyOutput(root, output, index, siblings) {
+			t.Errorf("output %d: tampered proof verifies", i)
+		}
+	}
+
+	if _, err := tree.Proof(uint64(len(outputs))); !errors.Is(err, rollup.ErrInvalidArgument) {
+		t.Fatalf("expected %v for a missing output, got %v", rollup.ErrInvalidArgument, err)
+	}
+}
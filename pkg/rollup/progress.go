@@ -0,0 +1,32 @@
+package rollup
+
+// progressStep is the smallest change in permille worth reporting from
+// ForEach, as every report pauses the machine.
+const progressStep = 10
+
+// Progresser tells the node how far the processing of the current request
+// is, in thousandths.
+type Progresser interface {
+	Progress(permille uint32) error
+}
+
+// ForEach calls fn for each i in [0, n), stopping at the first error, and
+// reports progress to p along the way, every percent at most, and once all
+// items are done.
+func ForEach(p Progresser, n int, fn func(i int) error) error {
+	var reported uint32
+	for i := 0; i < n; i++ {
+		if err := fn(i); err != nil {
+			return err
+		}
+
+		permille := uint32(uint64(i+1) * 1000 / uint64(n))
+		if permille >= reported+progressStep || i == n-1 {
+			if err := p.Progress(permille); err != nil {
+				return err
+			}
+			reported = permille
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package rollup_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+// progress records the values reported to it.
+type progress []uint32
+
+func (p *progress) Progress(permille uint32) error {
+	*p = append(*p, permille)
+	return nil
+}
+
+func TestForEach(t *testing.T) {
+	for _, tc := range []struct {
+		n    int
+		want []uint32
+	}{
+		{0, nil},
+		{1, []uint32{1000}},
+		{3, []uint32{333, 666, 1000}},
+		{50, steps(20, 1000, 20)},
+		{1000, steps(10, 1000, 10)},
+		{1001, steps(10, 1000, 10)},
+	} {
+		var p progress
+		calls := 0
+		if err := rollup.ForEach(&p, tc.n, func(i int) error {
+			if i != calls {
+				t.Fatalf("n=%d: called with %d, want %d", tc.n, i, calls)
+			}
+			calls++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if calls != tc.n {
+			t.Fatalf("n=%d: fn called %d times", tc.n, calls)
+		}
+		if !reflect.DeepEqual([]uint32(p), tc.want) {
+			t.Fatalf("n=%d: reported %v, want %v", tc.n, p, tc.want)
+		}
+	}
+}
+
+func TestForEachStopsAtTheFirstError(t *testing.T) {
+	errStop := errors.New("stop")
+
+	var p progress
+	calls := 0
+	err := rollup.ForEach(&p, 200, func(i int) error {
+		calls++
+		if i == 100 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || calls != 101 {
+		t.Fatalf("expected to stop at item 100, got %v after %d calls", err, calls)
+	}
+	if len(p) == 0 || p[len(p)-1] != 500 {
+		t.Fatalf("expected progress up to 500, got %v", p)
+	}
+}
+
+// steps returns from, from+step, ... up to to.
+func steps(from, to, step uint32) []uint32 {
+	var s []uint32
+	for v := from; v <= to; v += step {
+		s = append(s, v)
+	}
+	return s
+}
@@ -11,7 +11,6 @@ package rollup
 import "C"
 
 import (
-	"fmt"
 	"math/big"
 	"unsafe"
 
@@ -26,7 +25,7 @@ func New() (*Rollup, error) {
 	r := &Rollup{}
 	rc := C.cmt_rollup_init(&r.rollup)
 	if rc != 0 {
-		return nil, fmt.Errorf("cmt_rollup_init failed: %d", rc)
+		return nil, newError("cmt_rollup_init", int(rc))
 	}
 	return r, nil
 }
@@ -47,7 +46,7 @@ func (r *Rollup) EmitNotice(payload []byte) (uint64, error) {
 	var index C.uint64_t
 	rc := C.cmt_rollup_emit_notice(&r.rollup, &cPayload, &index)
 	if rc != 0 {
-		return 0, fmt.Errorf("cmt_rollup_emit_notice failed: %d", rc)
+		return 0, newError("cmt_rollup_emit_notice", int(rc))
 	}
 	return uint64(index), nil
 }
@@ -73,7 +72,7 @@ func (r *Rollup) EmitVoucher(address common.Address, value *big.Int, data []byte
 	var index C.uint64_t
 	rc := C.cmt_rollup_emit_voucher(&r.rollup, &cAddress, &cValue, &cData, &index)
 	if rc != 0 {
-		return 0, fmt.Errorf("cmt_rollup_emit_voucher failed: %d", rc)
+		return 0, newError("cmt_rollup_emit_voucher", int(rc))
 	}
 	return uint64(index), nil
 }
@@ -93,7 +92,7 @@ func (r *Rollup) EmitDelegateCallVoucher(address common.Address, data []byte) (u
 	var index C.uint64_t
 	rc := C.cmt_rollup_emit_delegate_call_voucher(&r.rollup, &cAddress, &cData, &index)
 	if rc != 0 {
-		return 0, fmt.Errorf("cmt_rollup_emit_delegate_call_voucher failed: %d", rc)
+		return 0, newError("cmt_rollup_emit_delegate_call_voucher", int(rc))
 	}
 	return uint64(index), nil
 }
@@ -108,7 +107,7 @@ func (r *Rollup) EmitReport(payload []byte) error {
 
 	rc := C.cmt_rollup_emit_report(&r.rollup, &cPayload)
 	if rc != 0 {
-		return fmt.Errorf("cmt_rollup_emit_report failed: %d", rc)
+		return newError("cmt_rollup_emit_report", int(rc))
 	}
 	return nil
 }
@@ -123,7 +122,7 @@ func (r *Rollup) EmitException(payload []byte) error {
 
 	rc := C.cmt_rollup_emit_exception(&r.rollup, &cPayload)
 	if rc != 0 {
-		return fmt.Errorf("cmt_rollup_emit_exception failed: %d", rc)
+		return newError("cmt_rollup_emit_exception", int(rc))
 	}
 	return nil
 }
@@ -132,7 +131,7 @@ func (r *Rollup) ReadAdvanceState() (*Advance, error) {
 	var cAdvance C.cmt_rollup_advance_t
 	rc := C.cmt_rollup_read_advance_state(&r.rollup, &cAdvance)
 	if rc != 0 {
-		return nil, fmt.Errorf("cmt_rollup_read_advance_state failed: %d", rc)
+		return nil, newError("cmt_rollup_read_advance_state", int(rc))
 	}
 
 	advance := &Advance{
@@ -164,7 +163,7 @@ func (r *Rollup) ReadInspectState() (*Inspect, error) {
 	var cInspect C.cmt_rollup_inspect_t
 	rc := C.cmt_rollup_read_inspect_state(&r.rollup, &cInspect)
 	if rc != 0 {
-		return nil, fmt.Errorf("cmt_rollup_read_inspect_state failed: %d", rc)
+		return nil, newError("cmt_rollup_read_inspect_state", int(rc))
 	}
 
 	inspect := &Inspect{}
@@ -181,7 +180,7 @@ func (r *Rollup) Finish(accept bool) (RequestType, uint32, error) {
 
 	rc := C.cmt_rollup_finish(&r.rollup, &finish)
 	if rc != 0 {
-		return 0, 0, fmt.Errorf("cmt_rollup_finish failed: %d", rc)
+		return 0, 0, newError("cmt_rollup_finish", int(rc))
 	}
 
 	var reqType RequestType
@@ -194,3 +193,43 @@ func (r *Rollup) Finish(accept bool) (RequestType, uint32, error) {
 
 	return reqType, uint32(finish.next_request_payload_length), nil
 }
+
+// OutputsRoot returns the root of the Merkle tree libcmt keeps of the
+// outputs emitted so far, to be checked against an OutputsTree.
+func (r *Rollup) OutputsRoot() (common.Hash, error) {
+	var root common.Hash
+	C.cmt_merkle_get_root_hash(&r.rollup.merkle[0], (*C.uint8_t)(unsafe.Pointer(&root[0])))
+	return root, nil
+}
+
+// Progress implements Progresser.
+func (r *Rollup) Progress(permille uint32) error {
+	rc := C.cmt_rollup_progress(&r.rollup, C.uint32_t(permille))
+	if rc != 0 {
+		return newError("cmt_rollup_progress", int(rc))
+	}
+	return nil
+}
+
+// GIORequest implements GIORequester, pausing the machine until the host
+// answers.
+func (r *Rollup) GIORequest(domain uint16, id []byte) (uint16, []byte, error) {
+	var req C.cmt_gio_t
+	req.domain = C.uint16_t(domain)
+	if len(id) > 0 {
+		req.id_length = C.uint32_t(len(id))
+		req.id = C.CBytes(id)
+		defer C.free(req.id)
+	}
+
+	rc := C.cmt_gio_request(&r.rollup, &req)
+	if rc != 0 {
+		return 0, nil, newError("cmt_gio_request", int(rc))
+	}
+
+	var data []byte
+	if req.response_data_length > 0 {
+		data = C.GoBytes(req.response_data, C.int(req.response_data_length))
+	}
+	return uint16(req.response_code), data, nil
+}
@@ -0,0 +1,128 @@
+//go:build !riscv64
+
+package rollup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// InputsEnv is the environment variable listing the input files of libcmt's
+// host mode, as comma-separated type:path pairs where type is 0 for an
+// advance and 1 for an inspect, e.g. "0:advance-0.bin,1:inspect-0.bin".
+const InputsEnv = "CMT_INPUTS"
+
+// HostInput is an input file of libcmt's host mode. An advance file holds an
+// EvmAdvance input and an inspect file holds the raw query payload.
+type HostInput struct {
+	Type RequestType
+	Path string
+}
+
+// ParseHostInputs parses a list of input files in the format of InputsEnv.
+func ParseHostInputs(spec string) ([]HostInput, error) {
+	var inputs []HostInput
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		typ, path, ok := strings.Cut(entry, ":")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("%w: host input %q", ErrInvalidArgument, entry)
+		}
+		switch strings.TrimSpace(typ) {
+		case "0":
+			inputs = append(inputs, HostInput{Type: RequestTypeAdvance, Path: path})
+		case "1":
+			inputs = append(inputs, HostInput{Type: RequestTypeInspect, Path: path})
+		default:
+			return nil, fmt.Errorf("%w: host input type %q", ErrInvalidArgument, typ)
+		}
+	}
+	return inputs, nil
+}
+
+// LoadHostInputs reads inputs and queues them in order, then closes the
+// input. As in libcmt's host mode, every output, report and exception the
+// application emits while processing an input is written next to its file,
+// named after it with the kind and a per-input sequence number: the outputs
+// of advance-0.bin go to advance-0.output-0.bin, advance-0.output-1.bin and
+// so on, its reports to advance-0.report-N.bin and an exception to
+// advance-0.exception-0.bin. Outputs are stored as they are emitted, so those
+// of a rejected advance are also written.
+//
+// Every file is read before any input is queued, so a missing or malformed
+// file queues nothing.
+func (r *Rollup) LoadHostInputs(inputs []HostInput) error {
+	queued := make([]*Input, 0, len(inputs))
+	for _, hi := range inputs {
+		data, err := os.ReadFile(hi.Path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrIOError, err)
+		}
+
+		in := &Input{Type: hi.Type, path: hi.Path}
+		switch hi.Type {
+		case RequestTypeAdvance:
+			if in.Advance, err = DecodeAdvance(data); err != nil {
+				return fmt.Errorf("%s: %w", hi.Path, err)
+			}
+		case RequestTypeInspect:
+			in.Inspect = &Inspect{Payload: data}
+		default:
+			return fmt.Errorf("%w: %s: request type %d", ErrInvalidArgument, hi.Path, hi.Type)
+		}
+		queued = append(queued, in)
+	}
+
+	go func() {
+		for _, in := range queued {
+			r.enqueue(in)
+		}
+		r.CloseInput()
+	}()
+	return nil
+}
+
+// loadHostInputsFromEnv loads the inputs listed in InputsEnv, if any.
+func (r *Rollup) loadHostInputsFromEnv() error {
+	spec, ok := os.LookupEnv(InputsEnv)
+	if !ok {
+		return nil
+	}
+	inputs, err := ParseHostInputs(spec)
+	if err != nil {
+		return err
+	}
+	return r.LoadHostInputs(inputs)
+}
+
+// store writes data as the seq-th file of the given kind for the request
+// being processed, when it was loaded from a host input file.
+func (r *Rollup) store(kind string, seq int, data []byte) error {
+	if r.current == nil || r.current.path == "" {
+		return nil
+	}
+
+	path := r.current.path
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(path, ext) + "." + kind + "-" + strconv.Itoa(seq) + ext
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return fmt.Errorf("%w: %v", ErrIOError, err)
+	}
+	return nil
+}
+
+// storeOutput writes the last output of the request being processed.
+func (r *Rollup) storeOutput(data []byte) error {
+	if r.current == nil {
+		return nil
+	}
+	in := r.current
+	return r.store("output", len(in.Vouchers)+len(in.DelegateCallVouchers)+len(in.Notices)-1, data)
+}
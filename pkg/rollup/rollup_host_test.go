@@ -0,0 +1,162 @@
+//go:build !riscv64
+
+package rollup_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+func TestParseHostInputs(t *testing.T) {
+	inputs, err := rollup.ParseHostInputs(" 0:advance-0.bin, 1:inspect-0.bin,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rollup.HostInput{
+		{Type: rollup.RequestTypeAdvance, Path: "advance-0.bin"},
+		{Type: rollup.RequestTypeInspect, Path: "inspect-0.bin"},
+	}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Fatalf("got %+v, want %+v", inputs, want)
+	}
+
+	for _, spec := range []string{"advance-0.bin", "0:", "2:advance-0.bin"} {
+		if _, err := rollup.ParseHostInputs(spec); !errors.Is(err, rollup.ErrInvalidArgument) {
+			t.Errorf("%q: expected %v, got %v", spec, rollup.ErrInvalidArgument, err)
+		}
+	}
+}
+
+func TestHostInputs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	advance := func(name string, index uint64, payload string) string {
+		data, err := rollup.EncodeAdvance(&rollup.Advance{Metadata: rollup.Metadata{MsgSender: sender, Index: index}, Payload: []byte(payload)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return write(name, data)
+	}
+
+	spec := "0:" + advance("advance-0.bin", 0, "accept") +
+		",1:" + write("inspect-0.bin", []byte("query")) +
+		",0:" + advance("advance-1.bin", 1, "raise")
+	t.Setenv(rollup.InputsEnv, spec)
+
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serve the inputs as an app would, until the end of the input.
+	for {
+		reqType, _, err := r.Finish(true)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reqType == rollup.RequestTypeInspect {
+			inspect, err := r.ReadInspectState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.EmitReport(append([]byte("report of "), inspect.Payload...)); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		advance, err := r.ReadAdvanceState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.EmitNotice(advance.Payload); err != nil {
+			t.Fatal(err)
+		}
+		if string(advance.Payload) == "raise" {
+			if err := r.EmitException([]byte("boom")); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if _, err := r.EmitVoucher(sender, big.NewInt(1), advance.Payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.EmitReport([]byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.EmitReport([]byte("second")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string][]byte{
+		"advance-0.output-0.bin":    rollup.EncodeNotice(rollup.Notice{Payload: []byte("accept")}),
+		"advance-0.output-1.bin":    rollup.EncodeVoucher(rollup.Voucher{Index: 1, Destination: sender, Value: big.NewInt(1), Payload: []byte("accept")}),
+		"advance-0.report-0.bin":    []byte("first"),
+		"advance-0.report-1.bin":    []byte("second"),
+		"inspect-0.report-0.bin":    []byte("report of query"),
+		"advance-1.output-0.bin":    rollup.EncodeNotice(rollup.Notice{Index: 2, Payload: []byte("raise")}),
+		"advance-1.exception-0.bin": []byte("boom"),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	var wantNames []string
+	for name := range want {
+		wantNames = append(wantNames, name)
+	}
+	wantNames = append(wantNames, "advance-0.bin", "advance-1.bin", "inspect-0.bin")
+	sort.Strings(wantNames)
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("got files %q, want %q", names, wantNames)
+	}
+
+	for name, data := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: got %x, want %x", name, got, data)
+		}
+	}
+}
+
+func TestHostInputsQueueNothingOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "advance-0.bin")
+	if err := os.WriteFile(path, []byte("not an advance"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, spec := range []string{"0:" + path, "1:" + filepath.Join(dir, "missing.bin")} {
+		t.Setenv(rollup.InputsEnv, spec)
+		if _, err := rollup.New(); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
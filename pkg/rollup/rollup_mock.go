@@ -3,153 +3,550 @@
 package rollup
 
 import (
+	"fmt"
+	"log"
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// Mock-specific types for capturing emitted outputs during testing.
+// Input is a request served by the mock together with its outcome and the
+// outputs emitted while processing it.
+type Input struct {
+	Type    RequestType
+	Advance *Advance
+	Inspect *Inspect
+
+	// Finished is set once the application calls Finish or emits an
+	// exception; Accepted is only meaningful afterwards.
+	Finished  bool
+	Accepted  bool
+	Exception []byte
+
+	// Progress lists the progress reported while processing the request,
+	// in thousandths.
+	Progress []uint32
+
+	Vouchers             []Voucher
+	DelegateCallVouchers []DelegateCallVoucher
+	Notices              []Notice
+	Reports              []Report
+
+	// path is the host input file the request was loaded from, if any.
+	path string
+}
+
+func (in *Input) clone() Input {
+	c := *in
+	c.Vouchers = append([]Voucher(nil), in.Vouchers...)
+	c.DelegateCallVouchers = append([]DelegateCallVoucher(nil), in.DelegateCallVouchers...)
+	c.Notices = append([]Notice(nil), in.Notices...)
+	c.Reports = append([]Report(nil), in.Reports...)
+	c.Progress = append([]uint32(nil), in.Progress...)
+	return c
+}
 
-type Notice struct {
-	Payload []byte
+// Outputs returns the vouchers, delegate call vouchers and notices of the
+// request encoded as the machine emits them, in output index order.
+func (in *Input) Outputs() [][]byte {
+	return encodeOutputs(in.Vouchers, in.DelegateCallVouchers, in.Notices)
 }
 
-type Voucher struct {
-	Destination common.Address
-	Value       *big.Int
-	Payload     []byte
+func encodeOutputs(vouchers []Voucher, delegateCallVouchers []DelegateCallVoucher, notices []Notice) [][]byte {
+	type output struct {
+		index uint64
+		data  []byte
+	}
+	outputs := make([]output, 0, len(vouchers)+len(delegateCallVouchers)+len(notices))
+	for _, v := range vouchers {
+		outputs = append(outputs, output{v.Index, EncodeVoucher(v)})
+	}
+	for _, v := range delegateCallVouchers {
+		outputs = append(outputs, output{v.Index, EncodeDelegateCallVoucher(v)})
+	}
+	for _, n := range notices {
+		outputs = append(outputs, output{n.Index, EncodeNotice(n)})
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].index < outputs[j].index })
+
+	encoded := make([][]byte, len(outputs))
+	for i, o := range outputs {
+		encoded[i] = o.data
+	}
+	return encoded
 }
 
-type Report struct {
-	Payload []byte
+// State is application state the mock reverts along with the outputs of a
+// rejected advance, as the node does by reverting the machine. The mock
+// ledger implements it.
+type State interface {
+	Snapshot() error
+	Restore() error
 }
 
-type DelegateCallVoucher struct {
-	Destination common.Address
-	Payload     []byte
+// checkpoint marks the outputs emitted before the current request.
+type checkpoint struct {
+	vouchers             int
+	delegateCallVouchers int
+	notices              int
+	outputs              uint64
 }
 
+// queueSize is the number of requests that can wait in the mock before
+// Advance and Inspect block.
+const queueSize = 1024
+
 type Rollup struct {
 	mu                   sync.Mutex
 	vouchers             []Voucher
 	delegateCallVouchers []DelegateCallVoucher
 	notices              []Notice
 	reports              []Report
-	advances             []*Advance
-	inspects             []*Inspect
-	finished             bool
-	rejected             bool
-	advanceIdx           int
-	inspectIdx           int
+	outputs              uint64
+	inputs               []*Input
+	current              *Input
+	checkpoint           checkpoint
+	raised               bool
+	states               []State
+	next                 *Input
+	responders           map[uint16]GIOResponder
+
+	// queue holds requests waiting for Finish. queueMu guards sending on
+	// and closing it, and is separate from mu so that a blocked sender does
+	// not prevent the app from emitting outputs.
+	queueMu sync.Mutex
+	queue   chan *Input
+	closed  bool
 }
 
+// New creates a mock rollup. When InputsEnv is set, as for libcmt's host mode,
+// the input files it lists are loaded with LoadHostInputs.
 func New() (*Rollup, error) {
-	return &Rollup{}, nil
+	r := &Rollup{queue: make(chan *Input, queueSize)}
+	if err := r.loadHostInputsFromEnv(); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (r *Rollup) Close() error {
 	return nil
 }
 
+// Track makes the mock snapshot states before every request and restore them
+// when an advance is rejected or an inspect finishes, since the node never
+// keeps changes made while serving an inspect.
+func (r *Rollup) Track(states ...State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, states...)
+}
+
 func (r *Rollup) EmitVoucher(address common.Address, value *big.Int, data []byte) (uint64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.vouchers = append(r.vouchers, Voucher{
+	if err := r.checkRaised(); err != nil {
+		return 0, err
+	}
+
+	v := Voucher{
+		Index:       r.nextOutputIndex(),
 		Destination: address,
-		Value:       new(big.Int).Set(value),
+		Value:       voucherValue(value),
 		Payload:     append([]byte(nil), data...),
-	})
-	return uint64(len(r.vouchers) - 1), nil
+	}
+	r.vouchers = append(r.vouchers, v)
+	if r.current != nil {
+		r.current.Vouchers = append(r.current.Vouchers, v)
+	}
+	if err := r.storeOutput(EncodeVoucher(v)); err != nil {
+		return 0, err
+	}
+	return v.Index, nil
 }
 
 func (r *Rollup) EmitDelegateCallVoucher(address common.Address, data []byte) (uint64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.delegateCallVouchers = append(r.delegateCallVouchers, DelegateCallVoucher{
+	if err := r.checkRaised(); err != nil {
+		return 0, err
+	}
+
+	v := DelegateCallVoucher{
+		Index:       r.nextOutputIndex(),
 		Destination: address,
 		Payload:     append([]byte(nil), data...),
-	})
-	return uint64(len(r.delegateCallVouchers) - 1), nil
+	}
+	r.delegateCallVouchers = append(r.delegateCallVouchers, v)
+	if r.current != nil {
+		r.current.DelegateCallVouchers = append(r.current.DelegateCallVouchers, v)
+	}
+	if err := r.storeOutput(EncodeDelegateCallVoucher(v)); err != nil {
+		return 0, err
+	}
+	return v.Index, nil
 }
 
 func (r *Rollup) EmitNotice(payload []byte) (uint64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.notices = append(r.notices, Notice{Payload: append([]byte(nil), payload...)})
-	return uint64(len(r.notices) - 1), nil
+	if err := r.checkRaised(); err != nil {
+		return 0, err
+	}
+
+	n := Notice{Index: r.nextOutputIndex(), Payload: append([]byte(nil), payload...)}
+	r.notices = append(r.notices, n)
+	if r.current != nil {
+		r.current.Notices = append(r.current.Notices, n)
+	}
+	if err := r.storeOutput(EncodeNotice(n)); err != nil {
+		return 0, err
+	}
+	return n.Index, nil
 }
 
 func (r *Rollup) EmitReport(payload []byte) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.reports = append(r.reports, Report{Payload: append([]byte(nil), payload...)})
+	if err := r.checkRaised(); err != nil {
+		return err
+	}
+
+	report := Report{Payload: append([]byte(nil), payload...)}
+	r.reports = append(r.reports, report)
+	if r.current != nil {
+		r.current.Reports = append(r.current.Reports, report)
+		return r.store("report", len(r.current.Reports)-1, report.Payload)
+	}
 	return nil
 }
 
+// EmitException rejects the request being processed, recording payload as
+// its exception. As on the machine, the request is over: the next Finish
+// does not apply to it and outputs emitted until the next request starts
+// fail with ErrAlreadyFinished.
 func (r *Rollup) EmitException(payload []byte) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.rejected = true
+	if err := r.checkRaised(); err != nil {
+		return err
+	}
+	if r.current != nil {
+		r.current.Exception = append([]byte(nil), payload...)
+		err := r.store("exception", 0, r.current.Exception)
+		r.finish(false)
+		r.raised = true
+		return err
+	}
 	return nil
 }
 
-func (r *Rollup) Finish(accept bool) (RequestType, uint32, error) {
+func (r *Rollup) checkRaised() error {
+	if r.raised {
+		return fmt.Errorf("%w: the request raised an exception", ErrAlreadyFinished)
+	}
+	return nil
+}
+
+// Progress records permille in the Progress of the request being processed.
+func (r *Rollup) Progress(permille uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if permille > 1000 {
+		return fmt.Errorf("%w: progress %d above 1000", ErrInvalidArgument, permille)
+	}
+	if r.current != nil {
+		r.current.Progress = append(r.current.Progress, permille)
+	}
+	return nil
+}
+
+// GIOResponder answers the GIO requests of one domain in the mock.
+type GIOResponder func(id []byte) (code uint16, data []byte, err error)
+
+// HandleGIO makes the mock answer GIO requests for domain with h, replacing
+// any responder previously set for it.
+func (r *Rollup) HandleGIO(domain uint16, h GIOResponder) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.finished = true
+	if r.responders == nil {
+		r.responders = make(map[uint16]GIOResponder)
+	}
+	r.responders[domain] = h
+}
+
+// GIORequest answers with the responder set for domain with HandleGIO, and
+// fails with ErrInvalidArgument when there is none.
+func (r *Rollup) GIORequest(domain uint16, id []byte) (uint16, []byte, error) {
+	r.mu.Lock()
+	h := r.responders[domain]
+	r.mu.Unlock()
 
-	if r.advanceIdx < len(r.advances) {
-		return RequestTypeAdvance, uint32(len(r.advances[r.advanceIdx].Payload)), nil
+	if h == nil {
+		return 0, nil, fmt.Errorf("%w: no responder for GIO domain %#x", ErrInvalidArgument, domain)
 	}
-	if r.inspectIdx < len(r.inspects) {
-		return RequestTypeInspect, uint32(len(r.inspects[r.inspectIdx].Payload)), nil
+	return h(append([]byte(nil), id...))
+}
+
+// Keccak256Preimages returns a responder for Keccak256PreimageDomain that
+// knows the given preimages. Unknown hashes are answered with code 1 and no
+// data.
+func Keccak256Preimages(preimages ...[]byte) GIOResponder {
+	known := make(map[common.Hash][]byte, len(preimages))
+	for _, p := range preimages {
+		known[crypto.Keccak256Hash(p)] = append([]byte(nil), p...)
+	}
+	return func(id []byte) (uint16, []byte, error) {
+		data, ok := known[common.BytesToHash(id)]
+		if !ok || len(id) != common.HashLength {
+			return 1, nil, nil
+		}
+		return 0, data, nil
 	}
-	return RequestTypeAdvance, 0, nil
+}
+
+// Finish records accept for the request being processed and blocks until
+// another one is queued with Advance or Inspect. Requests are served in the
+// order they were queued. Once the queue is closed with CloseInput and
+// drained, Finish returns ErrEndOfInput.
+func (r *Rollup) Finish(accept bool) (RequestType, uint32, error) {
+	r.mu.Lock()
+	if r.current != nil {
+		r.finish(accept)
+	}
+	queue := r.queue
+	r.mu.Unlock()
+
+	next, ok := <-queue
+	if !ok {
+		return 0, 0, ErrEndOfInput
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next = next
+	if next.Type == RequestTypeAdvance {
+		return RequestTypeAdvance, uint32(len(next.Advance.Payload)), nil
+	}
+	return RequestTypeInspect, uint32(len(next.Inspect.Payload)), nil
 }
 
 func (r *Rollup) ReadAdvanceState() (*Advance, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.advanceIdx >= len(r.advances) {
+	if r.next == nil || r.next.Type != RequestTypeAdvance {
 		return nil, ErrNotInitialized
 	}
 
-	advance := r.advances[r.advanceIdx]
-	r.advanceIdx++
-	return advance, nil
+	in := r.next
+	r.next = nil
+	r.start(in)
+	return in.Advance, nil
 }
 
 func (r *Rollup) ReadInspectState() (*Inspect, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.inspectIdx >= len(r.inspects) {
+	if r.next == nil || r.next.Type != RequestTypeInspect {
 		return nil, ErrNotInitialized
 	}
 
-	inspect := r.inspects[r.inspectIdx]
-	r.inspectIdx++
-	return inspect, nil
+	in := r.next
+	r.next = nil
+	r.start(in)
+	return in.Inspect, nil
+}
+
+func (r *Rollup) start(in *Input) {
+	r.inputs = append(r.inputs, in)
+	r.current = in
+	r.raised = false
+	r.checkpoint = checkpoint{
+		vouchers:             len(r.vouchers),
+		delegateCallVouchers: len(r.delegateCallVouchers),
+		notices:              len(r.notices),
+		outputs:              r.outputs,
+	}
+	for _, s := range r.states {
+		if err := s.Snapshot(); err != nil {
+			log.Printf("[rollup] failed to snapshot state: %v", err)
+		}
+	}
+}
+
+// finish ends the current request. A rejected advance loses its vouchers
+// and notices, whose indices are reused, but keeps its reports, as on the
+// node.
+func (r *Rollup) finish(accept bool) {
+	in := r.current
+	in.Finished = true
+	in.Accepted = accept
+	r.current = nil
+
+	if in.Type == RequestTypeAdvance && !accept {
+		r.vouchers = r.vouchers[:r.checkpoint.vouchers]
+		r.delegateCallVouchers = r.delegateCallVouchers[:r.checkpoint.delegateCallVouchers]
+		r.notices = r.notices[:r.checkpoint.notices]
+		r.outputs = r.checkpoint.outputs
+		in.Vouchers = nil
+		in.DelegateCallVouchers = nil
+		in.Notices = nil
+	}
+	if in.Type == RequestTypeInspect || !accept {
+		for _, s := range r.states {
+			if err := s.Restore(); err != nil {
+				log.Printf("[rollup] failed to restore state: %v", err)
+			}
+		}
+	}
+}
+
+func (r *Rollup) nextOutputIndex() uint64 {
+	index := r.outputs
+	r.outputs++
+	return index
 }
 
+// Advance queues an advance request. It may be called from any goroutine,
+// blocks while the queue is full and panics after CloseInput.
 func (r *Rollup) Advance(advance *Advance) {
+	r.enqueue(&Input{Type: RequestTypeAdvance, Advance: advance})
+}
+
+// AdvanceRaw queues an advance given as the EvmAdvance input the machine
+// reads, like Advance.
+func (r *Rollup) AdvanceRaw(data []byte) error {
+	advance, err := DecodeAdvance(data)
+	if err != nil {
+		return err
+	}
+	r.Advance(advance)
+	return nil
+}
+
+// Inspect queues an inspect request, like Advance.
+func (r *Rollup) Inspect(inspect *Inspect) {
+	r.enqueue(&Input{Type: RequestTypeInspect, Inspect: inspect})
+}
+
+func (r *Rollup) enqueue(in *Input) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	if r.closed {
+		panic("rollup: request queued after CloseInput")
+	}
+	r.queue <- in
+}
+
+// CloseInput marks the end of the input. Requests already queued are still
+// served, after which Finish returns ErrEndOfInput and router.Run returns.
+func (r *Rollup) CloseInput() {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+}
+
+// Inputs returns every request read so far, in processing order, with the
+// outputs each one emitted. Rejected advances only keep their reports. The
+// last one is still being processed when its Finished flag is not set.
+func (r *Rollup) Inputs() []Input {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.advances = append(r.advances, advance)
+
+	inputs := make([]Input, len(r.inputs))
+	for i, in := range r.inputs {
+		inputs[i] = in.clone()
+	}
+	return inputs
 }
 
-func (r *Rollup) Inspect(inspect *Inspect) {
+// Vouchers returns every voucher emitted so far; see Inputs for them grouped
+// by request.
+func (r *Rollup) Vouchers() []Voucher {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.inspects = append(r.inspects, inspect)
+	return append([]Voucher(nil), r.vouchers...)
+}
+
+func (r *Rollup) DelegateCallVouchers() []DelegateCallVoucher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]DelegateCallVoucher(nil), r.delegateCallVouchers...)
+}
+
+func (r *Rollup) Notices() []Notice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Notice(nil), r.notices...)
+}
+
+// Outputs returns every voucher, delegate call voucher and notice emitted so
+// far, encoded as the machine emits them, so that the position of each one is
+// its output index.
+func (r *Rollup) Outputs() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return encodeOutputs(r.vouchers, r.delegateCallVouchers, r.notices)
+}
+
+// OutputsRoot returns the root of the Merkle tree of the outputs emitted so
+// far, as libcmt maintains it on the machine.
+func (r *Rollup) OutputsRoot() (common.Hash, error) {
+	return r.outputsTree().Root(), nil
+}
+
+// OutputProof returns the siblings proving the output at index against
+// OutputsRoot.
+func (r *Rollup) OutputProof(index uint64) ([]common.Hash, error) {
+	return r.outputsTree().Proof(index)
+}
+
+func (r *Rollup) outputsTree() *OutputsTree {
+	tree := &OutputsTree{}
+	for _, output := range r.Outputs() {
+		tree.Push(output)
+	}
+	return tree
+}
+
+// Reports returns every report emitted so far, including the diagnostic
+// reports of inputs rejected after a panic.
+func (r *Rollup) Reports() []Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Report(nil), r.reports...)
+}
+
+// Accepted returns the outcome of each finished request, in processing
+// order.
+func (r *Rollup) Accepted() []bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var accepted []bool
+	for _, in := range r.inputs {
+		if in.Finished {
+			accepted = append(accepted, in.Accepted)
+		}
+	}
+	return accepted
 }
 
 func (r *Rollup) Reset() {
@@ -160,10 +557,21 @@ func (r *Rollup) Reset() {
 	r.delegateCallVouchers = nil
 	r.notices = nil
 	r.reports = nil
-	r.advances = nil
-	r.inspects = nil
-	r.finished = false
-	r.rejected = false
-	r.advanceIdx = 0
-	r.inspectIdx = 0
+	r.outputs = 0
+	r.inputs = nil
+	r.current = nil
+	r.checkpoint = checkpoint{}
+	r.raised = false
+	r.next = nil
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	if r.closed {
+		r.queue = make(chan *Input, queueSize)
+		r.closed = false
+		return
+	}
+	for len(r.queue) > 0 {
+		<-r.queue
+	}
 }
@@ -0,0 +1,231 @@
+//go:build !riscv64
+
+package rollup_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+func TestAdvanceRawAndOutputs(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := rollup.EncodeAdvance(&rollup.Advance{Metadata: rollup.Metadata{MsgSender: sender, Index: 3}, Payload: []byte("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AdvanceRaw(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AdvanceRaw(raw[:10]); err == nil {
+		t.Fatal("expected a malformed input to be refused")
+	}
+
+	if _, _, err := r.Finish(true); err != nil {
+		t.Fatal(err)
+	}
+	advance, err := r.ReadAdvanceState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advance.MsgSender != sender || advance.Index != 3 || string(advance.Payload) != "hi" {
+		t.Fatalf("unexpected advance %+v", advance)
+	}
+
+	notice := rollup.Notice{Payload: []byte("notice")}
+	voucher := rollup.Voucher{Destination: app, Value: big.NewInt(1), Payload: []byte("voucher")}
+	if _, err := r.EmitNotice(notice.Payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.EmitVoucher(voucher.Destination, voucher.Value, voucher.Payload); err != nil {
+		t.Fatal(err)
+	}
+	voucher.Index = 1
+
+	want := [][]byte{rollup.EncodeNotice(notice), rollup.EncodeVoucher(voucher)}
+	inputs := r.Inputs()
+	for _, got := range [][][]byte{r.Outputs(), inputs[0].Outputs()} {
+		if len(got) != 2 || !bytes.Equal(got[0], want[0]) || !bytes.Equal(got[1], want[1]) {
+			t.Fatalf("expected the outputs in index order, got %x", got)
+		}
+	}
+}
+
+func TestEmitVoucherWithoutValue(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture := &rollup.Capture{}
+
+	for _, emitter := range []rollup.Emitter{r, capture} {
+		if _, err := emitter.EmitVoucher(common.HexToAddress("0x01"), nil, []byte{0xab}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, vouchers := range [][]rollup.Voucher{r.Vouchers(), capture.Vouchers} {
+		if len(vouchers) != 1 || vouchers[0].Value == nil || vouchers[0].Value.Sign() != 0 {
+			t.Fatalf("expected a single voucher of value zero, got %+v", vouchers)
+		}
+	}
+}
+
+func TestEmitAfterException(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := func() {
+		t.Helper()
+		r.Advance(&rollup.Advance{})
+		if _, _, err := r.Finish(true); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ReadAdvanceState(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start()
+	if err := r.EmitException([]byte("boom")); err != nil {
+		t.Fatal(err)
+	}
+	// The exception goes last, as it ends the request.
+	emits := []struct {
+		name string
+		emit func() error
+	}{
+		{"voucher", func() error {
+			_, err := r.EmitVoucher(common.HexToAddress("0x01"), big.NewInt(1), nil)
+			return err
+		}},
+		{"delegate call voucher", func() error {
+			_, err := r.EmitDelegateCallVoucher(common.HexToAddress("0x01"), nil)
+			return err
+		}},
+		{"notice", func() error {
+			_, err := r.EmitNotice(nil)
+			return err
+		}},
+		{"report", func() error { return r.EmitReport(nil) }},
+		{"exception", func() error { return r.EmitException(nil) }},
+	}
+	for _, e := range emits {
+		if err := e.emit(); !errors.Is(err, rollup.ErrAlreadyFinished) {
+			t.Errorf("%s: expected %v after an exception, got %v", e.name, rollup.ErrAlreadyFinished, err)
+		}
+	}
+	if in := r.Inputs()[0]; in.Accepted || string(in.Exception) != "boom" || len(in.Reports) != 0 {
+		t.Fatalf("expected the input to end with its exception alone, got %+v", in)
+	}
+
+	// The next request emits as usual.
+	start()
+	for _, e := range emits {
+		if err := e.emit(); err != nil {
+			t.Errorf("%s: %v", e.name, err)
+		}
+	}
+}
+
+func TestKeccak256Preimage(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	preimage := []byte("preimage")
+	hash := crypto.Keccak256Hash(preimage)
+
+	if _, err := rollup.Keccak256Preimage(r, hash); !errors.Is(err, rollup.ErrInvalidArgument) {
+		t.Fatalf("expected %v without a responder, got %v", rollup.ErrInvalidArgument, err)
+	}
+
+	r.HandleGIO(rollup.Keccak256PreimageDomain, rollup.Keccak256Preimages(preimage))
+	data, err := rollup.Keccak256Preimage(r, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, preimage) {
+		t.Fatalf("got %q, want %q", data, preimage)
+	}
+
+	if _, err := rollup.Keccak256Preimage(r, crypto.Keccak256Hash([]byte("unknown"))); !errors.Is(err, rollup.ErrPreimageNotFound) {
+		t.Fatalf("expected %v for an unknown hash, got %v", rollup.ErrPreimageNotFound, err)
+	}
+
+	// A host answering with other data is caught by the hash check.
+	r.HandleGIO(rollup.Keccak256PreimageDomain, func([]byte) (uint16, []byte, error) {
+		return 0, []byte("other"), nil
+	})
+	if _, err := rollup.Keccak256Preimage(r, hash); !errors.Is(err, rollup.ErrInvalidArgument) || errors.Is(err, rollup.ErrPreimageNotFound) {
+		t.Fatalf("expected %v for a mismatching preimage, got %v", rollup.ErrInvalidArgument, err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Advance(&rollup.Advance{})
+	if _, _, err := r.Finish(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadAdvanceState(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rollup.ForEach(r, 4, func(int) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Progress(1001); !errors.Is(err, rollup.ErrInvalidArgument) {
+		t.Fatalf("expected %v, got %v", rollup.ErrInvalidArgument, err)
+	}
+	if got := r.Inputs()[0].Progress; len(got) != 4 || got[0] != 250 || got[3] != 1000 {
+		t.Fatalf("expected the mock to record the progress, got %v", got)
+	}
+}
+
+func TestOutputsRootAndProofs(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.EmitNotice([]byte("notice")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.EmitVoucher(common.HexToAddress("0x01"), big.NewInt(1), []byte("voucher")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.EmitDelegateCallVoucher(common.HexToAddress("0x02"), []byte("delegate")); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := r.OutputsRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := r.Outputs()
+	if len(outputs) != 3 {
+		t.Fatalf("expected 3 outputs, got %d", len(outputs))
+	}
+	for i, output := range outputs {
+		siblings, err := r.OutputProof(uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rollup.VerifyOutput(root, output, uint64(i), siblings) {
+			t.Errorf("output %d: proof does not verify against the outputs root", i)
+		}
+	}
+}
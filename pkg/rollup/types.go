@@ -1,6 +1,8 @@
 package rollup
 
 import (
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -29,3 +31,28 @@ type Advance struct {
 type Inspect struct {
 	Payload []byte
 }
+
+// Outputs. Vouchers, delegate call vouchers and notices share a single
+// sequence of output indices.
+
+type Notice struct {
+	Index   uint64
+	Payload []byte
+}
+
+type Voucher struct {
+	Index       uint64
+	Destination common.Address
+	Value       *big.Int
+	Payload     []byte
+}
+
+type Report struct {
+	Payload []byte
+}
+
+type DelegateCallVoucher struct {
+	Index       uint64
+	Destination common.Address
+	Payload     []byte
+}
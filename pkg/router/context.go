@@ -0,0 +1,121 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+// Context carries a single advance or inspect request through middlewares
+// and handlers. Exactly one of Advance and Inspect is set; Metadata is the
+// zero value for inspects, which have none.
+type Context struct {
+	Type     rollup.RequestType
+	Advance  *rollup.Advance
+	Inspect  *rollup.Inspect
+	Metadata rollup.Metadata
+	Ledger   *ledger.Ledger
+	Params   Params
+
+	emitter rollup.Emitter
+	values  map[string]interface{}
+	signer  common.Address
+	relayed bool
+}
+
+func NewAdvanceContext(r rollup.Emitter, l *ledger.Ledger, advance *rollup.Advance) *Context {
+	return &Context{
+		Type:     rollup.RequestTypeAdvance,
+		Advance:  advance,
+		Metadata: advance.Metadata,
+		Ledger:   l,
+		emitter:  r,
+	}
+}
+
+func NewInspectContext(r rollup.Emitter, l *ledger.Ledger, inspect *rollup.Inspect) *Context {
+	return &Context{
+		Type:    rollup.RequestTypeInspect,
+		Inspect: inspect,
+		Ledger:  l,
+		emitter: r,
+	}
+}
+
+func (c *Context) Payload() []byte {
+	if c.Advance != nil {
+		return c.Advance.Payload
+	}
+	if c.Inspect != nil {
+		return c.Inspect.Payload
+	}
+	return nil
+}
+
+// Sender is the account acting in this request: the signer of a relayed
+// meta-transaction, otherwise the msg_sender of an advance, and the zero
+// address for inspects.
+func (c *Context) Sender() common.Address {
+	if c.relayed {
+		return c.signer
+	}
+	return c.Metadata.MsgSender
+}
+
+// Relayed reports whether the advance is a meta-transaction, in which case
+// Metadata.MsgSender is the relayer rather than Sender.
+func (c *Context) Relayed() bool {
+	return c.relayed
+}
+
+// Progress implements rollup.Progresser, so that Context can be passed to
+// rollup.ForEach. It does nothing when outputs are captured, as in
+// simulations.
+func (c *Context) Progress(permille uint32) error {
+	if p, ok := c.emitter.(rollup.Progresser); ok {
+		return p.Progress(permille)
+	}
+	return nil
+}
+
+func (c *Context) Notice(payload []byte) (uint64, error) {
+	return c.emitter.EmitNotice(payload)
+}
+
+func (c *Context) Report(payload []byte) error {
+	return c.emitter.EmitReport(payload)
+}
+
+func (c *Context) Voucher(v *parser.Voucher) (uint64, error) {
+	return c.emitter.EmitVoucher(v.Destination, v.Value, v.Payload)
+}
+
+func (c *Context) DelegateCallVoucher(v *parser.DelegateCallVoucher) (uint64, error) {
+	return c.emitter.EmitDelegateCallVoucher(v.Destination, v.Payload)
+}
+
+// Reject emits reason as a report and returns an ErrRejected error for the
+// handler to return, so the request is rejected with an explanation.
+func (c *Context) Reject(reason string) error {
+	if err := c.Report([]byte(reason)); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrRejected, reason)
+}
+
+// Set stores a value for the rest of the request, typically by a middleware
+// for a handler further down the chain.
+func (c *Context) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+func (c *Context) Get(key string) (interface{}, bool) {
+	value, ok := c.values[key]
+	return value, ok
+}
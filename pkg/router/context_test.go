@@ -0,0 +1,60 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestContextValues(t *testing.T) {
+	rt := newRouter(t)
+
+	var got interface{}
+	rt.Use(func(next Handler) Handler {
+		return func(ctx *Context) error {
+			ctx.Set("sender", ctx.Sender())
+			return next(ctx)
+		}
+	})
+	rt.HandleFallback(func(ctx *Context) error {
+		got, _ = ctx.Get("sender")
+		return nil
+	})
+	rt.HandleInspect(func(ctx *Context) error {
+		got, _ = ctx.Get("sender")
+		return nil
+	})
+
+	if !advance(t, rt, alice, []byte("x")) || got != alice {
+		t.Fatalf("expected the sender set by the middleware, got %v", got)
+	}
+	if !inspect(t, rt, []byte("x")) || got != (common.Address{}) {
+		t.Fatalf("expected no sender for an inspect, got %v", got)
+	}
+	if _, ok := (&Context{}).Get("missing"); ok {
+		t.Fatal("expected no value for a missing key")
+	}
+}
+
+func TestContextReject(t *testing.T) {
+	rt := newRouter(t)
+
+	var err error
+	rt.HandleFallback(func(ctx *Context) error {
+		err = ctx.Reject("not today")
+		return err
+	})
+
+	if advance(t, rt, alice, []byte("x")) {
+		t.Fatal("expected the advance to be rejected")
+	}
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("expected %v, got %v", ErrRejected, err)
+	}
+	if reports := mock(rt).Reports(); len(reports) != 1 || string(reports[0].Payload) != "not today" {
+		t.Fatalf("expected the reason to be reported, got %q", reports)
+	}
+}
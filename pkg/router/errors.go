@@ -0,0 +1,19 @@
+package router
+
+import "errors"
+
+var (
+	ErrNoHandler        = errors.New("no handler for input")
+	ErrInvalidSignature = errors.New("invalid function signature")
+	ErrInvalidHandler   = errors.New("invalid handler")
+	ErrMissingParam     = errors.New("missing parameter")
+	ErrInvalidParam     = errors.New("invalid parameter")
+	ErrPanic            = errors.New("handler panicked")
+	ErrPayloadTooLarge  = errors.New("payload too large")
+	ErrSenderNotAllowed = errors.New("sender not allowed")
+	ErrRejected         = errors.New("rejected")
+	ErrNoLedger         = errors.New("router has no ledger")
+	ErrInvalidMetaTx    = errors.New("invalid meta-transaction")
+	ErrInvalidNonce     = errors.New("invalid nonce")
+	ErrReservedToken    = errors.New("reserved token")
+)
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+type Params map[string]string
+
+func (p Params) Get(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrMissingParam, name)
+	}
+	return value, nil
+}
+
+func (p Params) Address(name string) (common.Address, error) {
+	value, err := p.Get(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("%w: %s: not an address: %q", ErrInvalidParam, name, value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+// Hash accepts any 0x-prefixed value of up to 32 bytes and left-pads it, so
+// an address can be used wherever an account ID is expected.
+func (p Params) Hash(name string) (common.Hash, error) {
+	value, err := p.Get(name)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	b, err := hexutil.Decode(value)
+	if err != nil || len(b) > common.HashLength {
+		return common.Hash{}, fmt.Errorf("%w: %s: not a hash: %q", ErrInvalidParam, name, value)
+	}
+	return common.BytesToHash(b), nil
+}
+
+func (p Params) BigInt(name string) (*big.Int, error) {
+	value, err := p.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(value, 0)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: not an integer: %q", ErrInvalidParam, name, value)
+	}
+	return n, nil
+}
+
+type inspectRoute struct {
+	segments []string
+	handler  Handler
+}
+
+// HandleInspectRoute registers h for inspect requests matching pattern, a
+// slash-separated path where segments in braces capture parameters, e.g.
+// "balance/{account}/{token}". JSON-RPC requests are matched by treating the
+// method and its params as path segments, so the pattern above also serves
+// {"method":"balance","params":["0x...","0x..."]}. Routes are tried in
+// registration order; requests matching no route go to the HandleInspect
+// handler, if any. Captured parameters are available through Context.Params.
+func (rt *Router) HandleInspectRoute(pattern string, h Handler, mws ...Middleware) {
+	rt.inspectRoutes = append(rt.inspectRoutes, &inspectRoute{
+		segments: splitPath(pattern),
+		handler:  chain(h, mws),
+	})
+}
+
+func (rt *Router) routeInspect(inspect *rollup.Inspect) (Handler, Params) {
+	segments := inspectSegments(inspect.Payload)
+	for _, route := range rt.inspectRoutes {
+		if params, ok := route.match(segments); ok {
+			return route.handler, params
+		}
+	}
+	return rt.inspect, nil
+}
+
+func (route *inspectRoute) match(segments []string) (Params, bool) {
+	if len(segments) != len(route.segments) {
+		return nil, false
+	}
+
+	params := make(Params)
+	for i, segment := range route.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func inspectSegments(payload []byte) []string {
+	if req, err := parser.DecodeInspectRequest(payload); err == nil {
+		return append([]string{req.Method}, req.Params...)
+	}
+	return splitPath(string(payload))
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+)
+
+// HandleLedgerInspect registers the built-in queries over the router's ledger,
+// answering with a single report holding the amount as a 32-byte big-endian
+// integer:
+//
+//	ledger_getBalance/{account}[/{token}[/{tokenId}[/{data}]]]
+//	ledger_getTotalSupply[/{token}[/{tokenId}[/{data}]]]
+//
+// The trailing data parameter is the execution layer data parser.DecodeInspect
+// accepts; it is matched but does not affect the answer.
+//
+// The same routes are also available under the shorter "balance" and
+// "supply" prefixes.
+func (rt *Router) HandleLedgerInspect(mws ...Middleware) error {
+	if rt.ledger == nil {
+		return ErrNoLedger
+	}
+
+	for _, prefix := range []string{"ledger_getBalance", "balance"} {
+		rt.HandleInspectRoute(prefix+"/{account}", handleBalance, mws...)
+		rt.HandleInspectRoute(prefix+"/{account}/{token}", handleBalance, mws...)
+		rt.HandleInspectRoute(prefix+"/{account}/{token}/{tokenId}", handleBalance, mws...)
+		rt.HandleInspectRoute(prefix+"/{account}/{token}/{tokenId}/{data}", handleBalance, mws...)
+	}
+	for _, prefix := range []string{"ledger_getTotalSupply", "supply"} {
+		rt.HandleInspectRoute(prefix, handleSupply, mws...)
+		rt.HandleInspectRoute(prefix+"/{token}", handleSupply, mws...)
+		rt.HandleInspectRoute(prefix+"/{token}/{tokenId}", handleSupply, mws...)
+		rt.HandleInspectRoute(prefix+"/{token}/{tokenId}/{data}", handleSupply, mws...)
+	}
+	return nil
+}
+
+func handleBalance(ctx *Context) error {
+	account, err := ctx.Params.Hash("account")
+	if err != nil {
+		return err
+	}
+
+	balance, err := getBalance(ctx.Ledger, account, ctx.Params)
+	if err != nil {
+		return err
+	}
+	return reportAmount(ctx, balance)
+}
+
+func handleSupply(ctx *Context) error {
+	supply, err := getSupply(ctx.Ledger, ctx.Params)
+	if err != nil {
+		return err
+	}
+	return reportAmount(ctx, supply)
+}
+
+func getBalance(l *ledger.Ledger, account common.Hash, params Params) (*big.Int, error) {
+	assetID, err := paramsAsset(l, params)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+
+	accountID, err := l.RetrieveAccountByID(account, ledger.RetrieveOperationFind)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+
+	balance, err := l.GetBalance(assetID, accountID)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+	return balance, nil
+}
+
+func getSupply(l *ledger.Ledger, params Params) (*big.Int, error) {
+	assetID, err := paramsAsset(l, params)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+
+	supply, err := l.GetTotalSupply(assetID)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+	return supply, nil
+}
+
+func paramsAsset(l *ledger.Ledger, params Params) (ledger.AssetID, error) {
+	if _, ok := params["token"]; !ok {
+		return l.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFind)
+	}
+
+	token, err := params.Address("token")
+	if err != nil {
+		return 0, err
+	}
+
+	if _, ok := params["tokenId"]; !ok {
+		return l.RetrieveAsset(token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFind)
+	}
+
+	tokenID, err := params.BigInt("tokenId")
+	if err != nil {
+		return 0, err
+	}
+	return l.RetrieveAsset(token, tokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+}
+
+// zeroIfNotFound answers zero for unknown accounts and assets, which simply
+// hold nothing, instead of rejecting the query.
+func zeroIfNotFound(err error) (*big.Int, error) {
+	if errors.Is(err, ledger.ErrAccountNotFound) || errors.Is(err, ledger.ErrAssetNotFound) {
+		return big.NewInt(0), nil
+	}
+	return nil, err
+}
+
+func reportAmount(ctx *Context, amount *big.Int) error {
+	report := make([]byte, 32)
+	amount.FillBytes(report)
+	return ctx.Report(report)
+}
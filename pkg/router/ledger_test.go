@@ -0,0 +1,129 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+)
+
+func TestHandleInspectRoute(t *testing.T) {
+	rt := newRouter(t)
+
+	var got Params
+	rt.HandleInspectRoute("hello/{name}", func(ctx *Context) error {
+		got = ctx.Params
+		return nil
+	})
+
+	for _, payload := range []string{"hello/world", "/hello/world/", `{"method":"hello","params":["world"]}`} {
+		got = nil
+		if !inspect(t, rt, []byte(payload)) {
+			t.Fatalf("%s: expected the route to accept", payload)
+		}
+		if name, err := got.Get("name"); err != nil || name != "world" {
+			t.Fatalf("%s: got name %q, %v", payload, name, err)
+		}
+	}
+
+	// Requests matching no route go to HandleInspect, or are rejected.
+	if inspect(t, rt, []byte("hello/big/world")) {
+		t.Fatal("expected an unrouted inspect to be rejected")
+	}
+	var ran string
+	rt.HandleInspect(record(&ran, "fallback"))
+	if !inspect(t, rt, []byte("hello")) || ran != "fallback" {
+		t.Fatal("expected an unrouted inspect to reach HandleInspect")
+	}
+}
+
+func TestParams(t *testing.T) {
+	params := Params{"account": "0x01", "token": "0x00000000000000000000000000000000000000aa", "amount": "0x10", "bad": "xyz"}
+
+	if h, err := params.Hash("account"); err != nil || h != common.HexToHash("0x01") {
+		t.Fatalf("Hash: got %s, %v", h.Hex(), err)
+	}
+	if a, err := params.Address("token"); err != nil || a != common.HexToAddress("0xaa") {
+		t.Fatalf("Address: got %s, %v", a.Hex(), err)
+	}
+	if n, err := params.BigInt("amount"); err != nil || n.Int64() != 16 {
+		t.Fatalf("BigInt: got %v, %v", n, err)
+	}
+	if _, err := params.Get("missing"); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("expected %v, got %v", ErrMissingParam, err)
+	}
+	for _, get := range []func(string) error{
+		func(name string) error { _, err := params.Hash(name); return err },
+		func(name string) error { _, err := params.Address(name); return err },
+		func(name string) error { _, err := params.BigInt(name); return err },
+	} {
+		if err := get("bad"); !errors.Is(err, ErrInvalidParam) {
+			t.Fatalf("expected %v, got %v", ErrInvalidParam, err)
+		}
+	}
+}
+
+func TestHandleLedgerInspect(t *testing.T) {
+	rt := newRouter(t)
+	if err := rt.HandleLedgerInspect(); err != nil {
+		t.Fatal(err)
+	}
+
+	l := rt.ledger
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	account := common.HexToHash("0x01")
+	assetID, err := l.RetrieveAsset(token, big.NewInt(7), ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountID, err := l.RetrieveAccountByID(account, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Deposit(assetID, accountID, big.NewInt(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	five := common.BigToHash(big.NewInt(5)).Bytes()
+	zero := make([]byte, 32)
+
+	for _, tc := range []struct {
+		payload string
+		want    []byte
+	}{
+		{"balance/" + account.Hex() + "/" + token.Hex() + "/7", five},
+		{"balance/" + account.Hex() + "/" + token.Hex() + "/8", zero},
+		{"balance/0x02/" + token.Hex() + "/7", zero},
+		{"balance/" + account.Hex(), zero},
+		{"supply/" + token.Hex() + "/7", five},
+		{"ledger_getTotalSupply/" + token.Hex(), zero},
+		{"supply", zero},
+		{`{"method":"ledger_getBalance","params":["` + account.Hex() + `","` + token.Hex() + `","7"]}`, five},
+		{`{"method":"ledger_getTotalSupply","params":["` + token.Hex() + `","7"]}`, five},
+		// Execution layer data is accepted and ignored.
+		{"balance/" + account.Hex() + "/" + token.Hex() + "/7/data", five},
+		{"supply/" + token.Hex() + "/7/data", five},
+		{`{"method":"ledger_getBalance","params":["` + account.Hex() + `","` + token.Hex() + `","7","data"]}`, five},
+		{`{"method":"ledger_getTotalSupply","params":["` + token.Hex() + `","7","data"]}`, five},
+	} {
+		before := len(mock(rt).Reports())
+		if !inspect(t, rt, []byte(tc.payload)) {
+			t.Fatalf("%s: rejected", tc.payload)
+		}
+		reports := mock(rt).Reports()[before:]
+		if len(reports) != 1 || !bytes.Equal(reports[0].Payload, tc.want) {
+			t.Fatalf("%s: got reports %x, want %x", tc.payload, reports, tc.want)
+		}
+	}
+
+	for _, payload := range []string{"balance/not-an-account", "supply/" + token.Hex() + "/not-an-id"} {
+		if inspect(t, rt, []byte(payload)) {
+			t.Fatalf("%s: expected invalid parameters to be rejected", payload)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package router
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+)
+
+// MetaTxSignature is the advance relayers send to execute a meta-transaction
+// on behalf of from.
+const MetaTxSignature = "executeMetaTransaction(address,uint256,bytes,bytes)"
+
+// nonceAsset names the reserved asset whose balance for an account is the next
+// meta-transaction nonce it must sign.
+const nonceAsset = "metatx:nonce"
+
+var (
+	eip712DomainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	metaTxTypeHash       = crypto.Keccak256Hash([]byte("MetaTransaction(address from,uint256 nonce,bytes data)"))
+)
+
+// EIP712Domain holds the parts of the signing domain chosen by the
+// application. The chain id and verifying contract are always taken from the
+// advance metadata, so a signature is only valid for one application on one
+// chain.
+type EIP712Domain struct {
+	Name    string
+	Version string
+}
+
+// MetaTxHash returns the EIP-712 digest from signs to have data executed with
+// the given nonce by the application app on chain chainID.
+func MetaTxHash(domain EIP712Domain, chainID uint64, app, from common.Address, nonce *big.Int, data []byte) common.Hash {
+	domainSeparator := crypto.Keccak256Hash(
+		eip712DomainTypeHash.Bytes(),
+		crypto.Keccak256([]byte(domain.Name)),
+		crypto.Keccak256([]byte(domain.Version)),
+		math.U256Bytes(new(big.Int).SetUint64(chainID)),
+		common.LeftPadBytes(app.Bytes(), 32),
+	)
+	structHash := crypto.Keccak256Hash(
+		metaTxTypeHash.Bytes(),
+		common.LeftPadBytes(from.Bytes(), 32),
+		math.U256Bytes(new(big.Int).Set(nonce)),
+		crypto.Keccak256(data),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator.Bytes(), structHash.Bytes())
+}
+
+// HandleMetaTransactions lets anyone relay advances signed by another
+// account. The relayer sends
+//
+//	executeMetaTransaction(address from, uint256 nonce, bytes data, bytes signature)
+//
+// where signature is from's 65-byte EIP-712 signature of
+// MetaTransaction(address from,uint256 nonce,bytes data) under domain. Once
+// the signature and nonce are verified, data is routed as if it were the
+// advance payload, to a method handler or the fallback, with from as
+// Context.Sender. Metadata.MsgSender remains the relayer.
+//
+// Nonces start at zero, increase by one with every executed meta-transaction
+// and are kept in the router's ledger under ReservedToken. They can be
+// queried through
+//
+//	metatx_getNonce/{account}
+//
+// which answers like the queries of HandleLedgerInspect.
+func (rt *Router) HandleMetaTransactions(domain EIP712Domain, mws ...Middleware) error {
+	if rt.ledger == nil {
+		return ErrNoLedger
+	}
+
+	relay := func(ctx *Context, from common.Address, nonce *big.Int, data []byte, signature []byte) error {
+		return rt.relay(ctx, domain, from, nonce, data, signature)
+	}
+	if err := rt.HandleMethod(MetaTxSignature, relay, mws...); err != nil {
+		return err
+	}
+	rt.HandleInspectRoute("metatx_getNonce/{account}", handleNonce, mws...)
+	return nil
+}
+
+// Nonce returns the nonce the next meta-transaction signed by account must
+// carry.
+func Nonce(l *ledger.Ledger, account common.Address) (*big.Int, error) {
+	assetID, err := ReservedAsset(l, nonceAsset, ledger.RetrieveOperationFind)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+	accountID, err := l.RetrieveAccountByAddress(account, ledger.RetrieveOperationFind)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+	nonce, err := l.GetBalance(assetID, accountID)
+	if err != nil {
+		return zeroIfNotFound(err)
+	}
+	return nonce, nil
+}
+
+func (rt *Router) relay(ctx *Context, domain EIP712Domain, from common.Address, nonce *big.Int, data []byte, signature []byte) error {
+	if ctx.relayed {
+		return fmt.Errorf("%w: nested meta-transaction", ErrInvalidMetaTx)
+	}
+
+	hash := MetaTxHash(domain, ctx.Metadata.ChainID, ctx.Metadata.AppContract, from, nonce, data)
+	signer, err := recoverSigner(hash, signature)
+	if err != nil {
+		return err
+	}
+	if signer != from {
+		return fmt.Errorf("%w: signed by %s, not %s", ErrInvalidMetaTx, signer.Hex(), from.Hex())
+	}
+
+	expected, err := Nonce(ctx.Ledger, from)
+	if err != nil {
+		return err
+	}
+	if nonce.Cmp(expected) != 0 {
+		return fmt.Errorf("%w: got %s, expected %s", ErrInvalidNonce, nonce, expected)
+	}
+	if err := incrementNonce(ctx.Ledger, from); err != nil {
+		return err
+	}
+
+	advance := *ctx.Advance
+	advance.Payload = data
+	ctx.Advance = &advance
+	ctx.signer = from
+	ctx.relayed = true
+
+	var h Handler
+	if len(data) >= 4 {
+		h = rt.methods[binary.BigEndian.Uint32(data[0:4])]
+	}
+	if h == nil {
+		h = rt.fallback
+	}
+	if h == nil {
+		return ErrNoHandler
+	}
+	return h(ctx)
+}
+
+func recoverSigner(hash common.Hash, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: signature must be %d bytes", ErrInvalidMetaTx, crypto.SignatureLength)
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	r := new(big.Int).SetBytes(sig[0:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[crypto.RecoveryIDOffset], r, s, true) {
+		return common.Address{}, fmt.Errorf("%w: malformed signature", ErrInvalidMetaTx)
+	}
+
+	pub, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidMetaTx, err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+func incrementNonce(l *ledger.Ledger, account common.Address) error {
+	assetID, err := ReservedAsset(l, nonceAsset, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	accountID, err := l.RetrieveAccountByAddress(account, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	return l.Deposit(assetID, accountID, big.NewInt(1))
+}
+
+func handleNonce(ctx *Context) error {
+	account, err := ctx.Params.Address("account")
+	if err != nil {
+		return err
+	}
+	nonce, err := Nonce(ctx.Ledger, account)
+	if err != nil {
+		return err
+	}
+	return reportAmount(ctx, nonce)
+}
@@ -0,0 +1,156 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+)
+
+var (
+	domain  = EIP712Domain{Name: "test", Version: "1"}
+	relayer = common.HexToAddress("0x00000000000000000000000000000000000000b1")
+)
+
+// newMetaTxRouter creates a router relaying meta-transactions to ping(),
+// which records its senders.
+func newMetaTxRouter(t *testing.T) (*Router, *[]common.Address) {
+	t.Helper()
+
+	rt := newRouter(t)
+	senders := new([]common.Address)
+	if err := rt.HandleMethod("ping()", func(ctx *Context) error {
+		*senders = append(*senders, ctx.Sender())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.HandleMetaTransactions(domain); err != nil {
+		t.Fatal(err)
+	}
+	return rt, senders
+}
+
+func newKey(t *testing.T, seed byte) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.ToECDSA(common.LeftPadBytes([]byte{seed}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// metaTx encodes a relay of data on behalf of from, signed by key for an
+// application with the zero address on chain 0, as advance sends them.
+func metaTx(t *testing.T, key *ecdsa.PrivateKey, from common.Address, nonce int64, data []byte) []byte {
+	t.Helper()
+
+	hash := MetaTxHash(domain, 0, common.Address{}, from, big.NewInt(nonce), data)
+	signature, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return call(t, MetaTxSignature, from, big.NewInt(nonce), data, signature)
+}
+
+func TestMetaTransactionReplay(t *testing.T) {
+	rt, senders := newMetaTxRouter(t)
+
+	key := newKey(t, 1)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	signed := metaTx(t, key, from, 0, call(t, "ping()"))
+	if !advance(t, rt, relayer, signed) {
+		t.Fatal("expected the meta-transaction to be accepted")
+	}
+	if advance(t, rt, relayer, signed) {
+		t.Fatal("expected the replayed meta-transaction to be rejected")
+	}
+	if len(*senders) != 1 || (*senders)[0] != from {
+		t.Fatalf("expected a single ping from %s, got %v", from.Hex(), *senders)
+	}
+
+	if !inspect(t, rt, []byte("metatx_getNonce/"+from.Hex())) {
+		t.Fatal("expected the nonce query to be accepted")
+	}
+	reports := mock(rt).Reports()
+	if len(reports) != 1 || !bytes.Equal(reports[0].Payload, common.BigToHash(big.NewInt(1)).Bytes()) {
+		t.Fatalf("expected nonce 1, got %x", reports)
+	}
+
+	if !advance(t, rt, relayer, metaTx(t, key, from, 1, call(t, "ping()"))) {
+		t.Fatal("expected the next nonce to be accepted")
+	}
+}
+
+func TestMetaTransactionRejectsInvalidRelays(t *testing.T) {
+	rt, senders := newMetaTxRouter(t)
+
+	key := newKey(t, 1)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	victim := crypto.PubkeyToAddress(newKey(t, 2).PublicKey)
+
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+	}{
+		{"foreign signature", metaTx(t, key, victim, 0, call(t, "ping()"))},
+		{"future nonce", metaTx(t, key, from, 1, call(t, "ping()"))},
+		{"short signature", call(t, MetaTxSignature, from, big.NewInt(0), call(t, "ping()"), []byte{1, 2, 3})},
+	} {
+		if advance(t, rt, relayer, tc.payload) {
+			t.Errorf("%s: expected the relay to be rejected", tc.name)
+		}
+	}
+	if len(*senders) != 0 {
+		t.Fatalf("expected no ping, got %v", *senders)
+	}
+
+	nonce, err := Nonce(rt.ledger, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce.Sign() != 0 {
+		t.Fatalf("expected the nonce to stay at 0, got %s", nonce)
+	}
+
+	if advance(t, rt, relayer, metaTx(t, key, from, 0, metaTx(t, key, from, 1, call(t, "ping()")))) {
+		t.Fatal("expected a nested meta-transaction to be rejected")
+	}
+	if len(*senders) != 0 {
+		t.Fatalf("expected no ping, got %v", *senders)
+	}
+}
+
+func TestNoncesAreReservedAssets(t *testing.T) {
+	rt, _ := newMetaTxRouter(t)
+
+	key := newKey(t, 1)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	if !advance(t, rt, relayer, metaTx(t, key, from, 0, call(t, "ping()"))) {
+		t.Fatal("expected the meta-transaction to be accepted")
+	}
+
+	assetID, err := ReservedAsset(rt.ledger, nonceAsset, ledger.RetrieveOperationFind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountID, err := rt.ledger.RetrieveAccountByAddress(from, ledger.RetrieveOperationFind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := rt.ledger.GetBalance(assetID, accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Int64() != 1 {
+		t.Fatalf("expected the nonce under the reserved token to be 1, got %s", balance)
+	}
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	contextType = reflect.TypeOf((*Context)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type method struct {
+	signature string
+	arguments abi.Arguments
+	handler   reflect.Value
+}
+
+// Selector returns the 4-byte function selector of a Solidity signature such
+// as "placeOrder(address,uint256,uint256)".
+func Selector(signature string) uint32 {
+	return binary.BigEndian.Uint32(crypto.Keccak256([]byte(signature))[:4])
+}
+
+// HandleMethod registers fn for advances whose payload starts with the
+// selector of signature. The remaining payload is ABI-decoded according to
+// the signature and passed to fn, which must have the form
+//
+//	func(ctx *Context, arg1 T1, ..., argN TN) error
+//
+// where each Ti is the Go type go-ethereum's abi package produces for the
+// corresponding Solidity type (e.g. common.Address, *big.Int, []byte).
+func (rt *Router) HandleMethod(signature string, fn interface{}, mws ...Middleware) error {
+	canonical, arguments, err := parseMethod(signature)
+	if err != nil {
+		return err
+	}
+
+	handler := reflect.ValueOf(fn)
+	if err := checkMethodHandler(handler.Type(), arguments); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidHandler, signature, err)
+	}
+
+	m := &method{
+		signature: canonical,
+		arguments: arguments,
+		handler:   handler,
+	}
+	rt.HandleSelector(Selector(canonical), m.handle, mws...)
+	return nil
+}
+
+// EncodeCall returns the payload of a call to signature with args, as
+// decoded by the handler HandleMethod registers for it.
+func EncodeCall(signature string, args ...interface{}) ([]byte, error) {
+	canonical, arguments, err := parseMethod(signature)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", canonical, err)
+	}
+	return append(binary.BigEndian.AppendUint32(nil, Selector(canonical)), packed...), nil
+}
+
+func (m *method) handle(ctx *Context) error {
+	values, err := m.arguments.Unpack(ctx.Advance.Payload[4:])
+	if err != nil {
+		return fmt.Errorf("%s: %w", m.signature, err)
+	}
+
+	in := make([]reflect.Value, 0, len(values)+1)
+	in = append(in, reflect.ValueOf(ctx))
+	for i, value := range values {
+		in = append(in, reflect.ValueOf(value).Convert(m.handler.Type().In(i+1)))
+	}
+
+	out := m.handler.Call(in)
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkMethodHandler(fnType reflect.Type, arguments abi.Arguments) error {
+	if fnType.Kind() != reflect.Func {
+		return fmt.Errorf("expected a function, got %s", fnType)
+	}
+	if fnType.NumIn() != len(arguments)+1 {
+		return fmt.Errorf("expected %d parameters, got %d", len(arguments)+1, fnType.NumIn())
+	}
+	if fnType.In(0) != contextType {
+		return fmt.Errorf("first parameter must be %s", contextType)
+	}
+	for i, argument := range arguments {
+		if !argument.Type.GetType().ConvertibleTo(fnType.In(i + 1)) {
+			return fmt.Errorf("parameter %d: cannot use %s as %s", i+1, argument.Type.GetType(), fnType.In(i+1))
+		}
+	}
+	if fnType.NumOut() != 1 || fnType.Out(0) != errorType {
+		return fmt.Errorf("expected a single error result")
+	}
+	return nil
+}
+
+// canonicalType expands the Solidity aliases uint, int and byte, alone or as
+// array elements, which go-ethereum's abi package does not accept.
+func canonicalType(t string) string {
+	base, suffix := t, ""
+	if i := strings.IndexByte(t, '['); i >= 0 {
+		base, suffix = t[:i], t[i:]
+	}
+	switch base {
+	case "uint", "int":
+		base += "256"
+	case "byte":
+		base = "bytes1"
+	}
+	return base + suffix
+}
+
+// parseMethod returns the canonical form of signature, from which the
+// selector is derived, and the arguments it takes. The canonical form uses
+// the canonical type names, e.g. uint256 for uint.
+func parseMethod(signature string) (string, abi.Arguments, error) {
+	name, types, err := parseSignature(signature)
+	if err != nil {
+		return "", nil, err
+	}
+
+	arguments := make(abi.Arguments, len(types))
+	canonicalTypes := make([]string, len(types))
+	for i, t := range types {
+		typ, err := abi.NewType(canonicalType(t), "", nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %s: %v", ErrInvalidSignature, signature, err)
+		}
+		arguments[i] = abi.Argument{Type: typ}
+		canonicalTypes[i] = typ.String()
+	}
+	return name + "(" + strings.Join(canonicalTypes, ",") + ")", arguments, nil
+}
+
+func parseSignature(signature string) (string, []string, error) {
+	signature = strings.ReplaceAll(signature, " ", "")
+
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return "", nil, fmt.Errorf("%w: %s", ErrInvalidSignature, signature)
+	}
+
+	name := signature[:open]
+	params := signature[open+1 : len(signature)-1]
+	if strings.ContainsAny(params, "()") {
+		return "", nil, fmt.Errorf("%w: tuples are not supported: %s", ErrInvalidSignature, signature)
+	}
+	if params == "" {
+		return name, nil, nil
+	}
+	return name, strings.Split(params, ","), nil
+}
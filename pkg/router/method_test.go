@@ -0,0 +1,143 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// call is EncodeCall, failing the test on an error.
+func call(t *testing.T, signature string, args ...interface{}) []byte {
+	t.Helper()
+
+	payload, err := EncodeCall(signature, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestHandleMethod(t *testing.T) {
+	rt := newRouter(t)
+
+	var (
+		gotTo     common.Address
+		gotAmount *big.Int
+		gotMemo   []byte
+	)
+	err := rt.HandleMethod("send(address, uint256, bytes)", func(ctx *Context, to common.Address, amount *big.Int, memo []byte) error {
+		gotTo, gotAmount, gotMemo = to, amount, memo
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !advance(t, rt, alice, call(t, "send(address,uint256,bytes)", bob, big.NewInt(42), []byte("memo"))) {
+		t.Fatal("expected the call to be accepted")
+	}
+	if gotTo != bob || gotAmount.Int64() != 42 || string(gotMemo) != "memo" {
+		t.Fatalf("unexpected arguments %s, %v, %q", gotTo.Hex(), gotAmount, gotMemo)
+	}
+
+	// Arguments that do not decode reject the advance.
+	if advance(t, rt, alice, call(t, "send(address,uint256,bytes)", bob, big.NewInt(42), []byte("memo"))[:40]) {
+		t.Fatal("expected truncated arguments to be rejected")
+	}
+}
+
+func TestHandleMethodPrecedence(t *testing.T) {
+	rt := newRouter(t)
+
+	var ran string
+	if err := rt.HandleMethod("ping()", record(&ran, "method")); err != nil {
+		t.Fatal(err)
+	}
+	rt.HandleSender(bob, record(&ran, "sender"))
+	rt.HandleFallback(record(&ran, "fallback"))
+
+	tests := []struct {
+		sender  common.Address
+		payload []byte
+		want    string
+	}{
+		{alice, call(t, "ping()"), "method"},
+		{bob, call(t, "ping()"), "sender"},
+		{alice, call(t, "pong()"), "fallback"},
+		{alice, []byte{0x01}, "fallback"},
+	}
+	for _, tc := range tests {
+		ran = ""
+		if !advance(t, rt, tc.sender, tc.payload) || ran != tc.want {
+			t.Fatalf("advance %x from %s: ran %q, want %q", tc.payload, tc.sender.Hex(), ran, tc.want)
+		}
+	}
+}
+
+func TestHandleMethodRejectsInvalidRegistrations(t *testing.T) {
+	rt := newRouter(t)
+
+	for _, signature := range []string{"f", "f(uint7)", "f((uint256,address))"} {
+		if err := rt.HandleMethod(signature, func(ctx *Context) error { return nil }); err == nil {
+			t.Errorf("%s: expected an error", signature)
+		}
+	}
+
+	handlers := []interface{}{
+		"not a function",
+		func(ctx *Context) error { return nil },
+		func(ctx *Context, v string) error { return nil },
+		func(ctx *Context, v *big.Int) {},
+	}
+	for i, fn := range handlers {
+		if err := rt.HandleMethod("set(uint256)", fn); err == nil {
+			t.Errorf("handler %d: expected an error", i)
+		}
+	}
+}
+
+func TestHandleMethodCanonicalizesAliases(t *testing.T) {
+	rt := newRouter(t)
+
+	var got []*big.Int
+	if err := rt.HandleMethod("set(uint, int[])", func(ctx *Context, v *big.Int, vs []*big.Int) error {
+		got = append([]*big.Int{v}, vs...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !advance(t, rt, alice, call(t, "set(uint256,int256[])", big.NewInt(42), []*big.Int{big.NewInt(-1)})) {
+		t.Fatal("expected the call to the canonical signature to be accepted")
+	}
+	if len(got) != 2 || got[0].Int64() != 42 || got[1].Int64() != -1 {
+		t.Fatalf("unexpected arguments %v", got)
+	}
+}
+
+func TestEncodeCall(t *testing.T) {
+	alias, err := EncodeCall("set(uint, int[])", big.NewInt(42), []*big.Int{big.NewInt(-1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical, err := EncodeCall("set(uint256,int256[])", big.NewInt(42), []*big.Int{big.NewInt(-1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(alias, canonical) || binary.BigEndian.Uint32(canonical) != Selector("set(uint256,int256[])") {
+		t.Fatalf("expected the aliases to encode as the canonical call, got %x and %x", alias, canonical)
+	}
+
+	if _, err := EncodeCall("set(uint256"); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if _, err := EncodeCall("set(uint256)", "42"); err == nil {
+		t.Fatal("expected an argument of the wrong type to fail")
+	}
+}
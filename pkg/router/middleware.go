@@ -0,0 +1,105 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// chain wraps h so that the first middleware is the outermost one.
+func chain(h Handler, mws []Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Logger logs every request with its metadata, outcome and duration.
+func Logger() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			start := time.Now()
+			err := next(ctx)
+			elapsed := time.Since(start)
+
+			status := "accepted"
+			if err != nil {
+				status = "rejected: " + err.Error()
+			}
+
+			if ctx.Advance != nil {
+				m := ctx.Metadata
+				log.Printf("[router] advance index=%d chain=%d app=%s sender=%s block=%d timestamp=%d payload=%dB %s (%s)",
+					m.Index, m.ChainID, m.AppContract.Hex(), m.MsgSender.Hex(), m.BlockNumber, m.BlockTimestamp, len(ctx.Payload()), status, elapsed)
+			} else {
+				log.Printf("[router] inspect payload=%dB %s (%s)", len(ctx.Payload()), status, elapsed)
+			}
+			return err
+		}
+	}
+}
+
+// Recoverer turns a panic in the wrapped handler into an ErrPanic error and
+// a diagnostic report, so the request is rejected instead of crashing the
+// application. Run already does this for every request; Recoverer lets a
+// middleware further out in the chain observe the resulting error.
+func Recoverer() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					err = recoverPanic(ctx, p)
+				}
+			}()
+			return next(ctx)
+		}
+	}
+}
+
+func recoverPanic(ctx *Context, p interface{}) error {
+	var msg string
+	if ctx.Advance != nil {
+		msg = fmt.Sprintf("panic at input %d: %v", ctx.Metadata.Index, p)
+	} else {
+		msg = fmt.Sprintf("panic at inspect: %v", p)
+	}
+
+	log.Printf("[router] %s\n%s", msg, debug.Stack())
+	if err := ctx.Report([]byte(msg)); err != nil {
+		log.Printf("[router] failed to emit panic report: %v", err)
+	}
+	return fmt.Errorf("%w: %v", ErrPanic, p)
+}
+
+// MaxPayloadSize rejects requests whose payload is larger than size bytes.
+func MaxPayloadSize(size int) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if n := len(ctx.Payload()); n > size {
+				return fmt.Errorf("%w: %d > %d bytes", ErrPayloadTooLarge, n, size)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// AllowSenders rejects advances whose msg_sender is not one of senders.
+// Inspect requests have no sender and are passed through.
+func AllowSenders(senders ...common.Address) Middleware {
+	allowed := make(map[common.Address]bool, len(senders))
+	for _, sender := range senders {
+		allowed[sender] = true
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if ctx.Advance != nil && !allowed[ctx.Metadata.MsgSender] {
+				return fmt.Errorf("%w: %s", ErrSenderNotAllowed, ctx.Metadata.MsgSender.Hex())
+			}
+			return next(ctx)
+		}
+	}
+}
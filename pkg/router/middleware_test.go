@@ -0,0 +1,128 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"errors"
+	"testing"
+)
+
+func accept(ctx *Context) error {
+	return nil
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	rt := newRouter(t)
+
+	var trace string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx *Context) error {
+				trace += name
+				return next(ctx)
+			}
+		}
+	}
+	rt.Use(mw("a"), mw("b"))
+	rt.HandleFallback(func(ctx *Context) error {
+		trace += "h"
+		return nil
+	}, mw("c"), mw("d"))
+
+	if !advance(t, rt, alice, []byte("x")) || trace != "abcdh" {
+		t.Fatalf("got trace %q", trace)
+	}
+
+	// Global middlewares also wrap the rejection of unhandled requests.
+	trace = ""
+	if inspect(t, rt, []byte("x")) || trace != "ab" {
+		t.Fatalf("got trace %q", trace)
+	}
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	rt := newRouter(t)
+	rt.Use(MaxPayloadSize(4))
+	rt.HandleFallback(accept)
+	rt.HandleInspect(accept)
+
+	if !advance(t, rt, alice, []byte("1234")) {
+		t.Fatal("expected a payload of the maximum size to be accepted")
+	}
+	if advance(t, rt, alice, []byte("12345")) {
+		t.Fatal("expected an oversized advance to be rejected")
+	}
+	if inspect(t, rt, []byte("12345")) {
+		t.Fatal("expected an oversized inspect to be rejected")
+	}
+}
+
+func TestAllowSenders(t *testing.T) {
+	rt := newRouter(t)
+	rt.HandleFallback(accept, AllowSenders(alice))
+	rt.HandleInspect(accept, AllowSenders(alice))
+
+	if !advance(t, rt, alice, []byte("x")) {
+		t.Fatal("expected an allowed sender to be accepted")
+	}
+	if advance(t, rt, bob, []byte("x")) {
+		t.Fatal("expected another sender to be rejected")
+	}
+	if !inspect(t, rt, []byte("x")) {
+		t.Fatal("expected an inspect to be passed through")
+	}
+}
+
+func TestRecoverer(t *testing.T) {
+	rt := newRouter(t)
+
+	var observed error
+	observe := func(next Handler) Handler {
+		return func(ctx *Context) error {
+			observed = next(ctx)
+			return observed
+		}
+	}
+	rt.HandleFallback(func(ctx *Context) error {
+		panic("boom")
+	}, observe, Recoverer())
+
+	if advance(t, rt, alice, []byte("x")) {
+		t.Fatal("expected a panicking handler to be rejected")
+	}
+	if !errors.Is(observed, ErrPanic) {
+		t.Fatalf("expected the outer middleware to observe %v, got %v", ErrPanic, observed)
+	}
+	if reports := mock(rt).Reports(); len(reports) != 1 || string(reports[0].Payload) != "panic at input 0: boom" {
+		t.Fatalf("expected a panic report, got %q", reports)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	rt := newRouter(t)
+	rt.HandleFallback(func(ctx *Context) error {
+		if string(ctx.Payload()) == "boom" {
+			panic("boom")
+		}
+		return nil
+	})
+	rt.HandleInspect(func(ctx *Context) error {
+		panic("inspect")
+	})
+
+	if advance(t, rt, alice, []byte("boom")) {
+		t.Fatal("expected a panicking advance to be rejected")
+	}
+	if inspect(t, rt, []byte("x")) {
+		t.Fatal("expected a panicking inspect to be rejected")
+	}
+	// The router keeps serving requests after a panic.
+	if !advance(t, rt, alice, []byte("x")) {
+		t.Fatal("expected the next advance to be accepted")
+	}
+
+	reports := mock(rt).Reports()
+	if len(reports) != 2 || string(reports[0].Payload) != "panic at input 0: boom" || string(reports[1].Payload) != "panic at inspect: inspect" {
+		t.Fatalf("unexpected panic reports %q", reports)
+	}
+}
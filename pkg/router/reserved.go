@@ -0,0 +1,25 @@
+package router
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+)
+
+// ReservedToken is the token address under which the framework keeps its own
+// state in the ledger, such as meta-transaction nonces and access roles, one
+// asset per token ID. No portal can deposit it and the wallet refuses to move
+// it, so that state only changes through the code that owns it.
+var ReservedToken = common.BytesToAddress(crypto.Keccak256([]byte("rollingopher:reserved")))
+
+// ReservedTokenID returns the token ID of the reserved asset called name.
+func ReservedTokenID(name string) *big.Int {
+	return new(big.Int).SetBytes(crypto.Keccak256([]byte(name)))
+}
+
+// ReservedAsset retrieves the reserved asset called name from l.
+func ReservedAsset(l *ledger.Ledger, name string, op ledger.RetrieveOperation) (ledger.AssetID, error) {
+	return l.RetrieveAsset(ReservedToken, ReservedTokenID(name), ledger.AssetTypeTokenAddressID, op)
+}
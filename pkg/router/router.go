@@ -0,0 +1,215 @@
+package router
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+type Router struct {
+	rollup        rollup.Interface
+	ledger        *ledger.Ledger
+	middlewares   []Middleware
+	senders       map[common.Address]Handler
+	methods       map[uint32]Handler
+	fallback      Handler
+	inspect       Handler
+	inspectRoutes []*inspectRoute
+
+	// last is the metadata of the last advance read, used for simulations.
+	last     rollup.Metadata
+	advanced bool
+}
+
+// New creates a router over r, the libcmt binding, the mock or any wrapper
+// around them. The ledger l is made available to handlers
+// through Context.Ledger and may be nil for applications that do not use one.
+func New(r rollup.Interface, l *ledger.Ledger) *Router {
+	return &Router{
+		rollup:  r,
+		ledger:  l,
+		senders: make(map[common.Address]Handler),
+		methods: make(map[uint32]Handler),
+	}
+}
+
+func (rt *Router) Ledger() *ledger.Ledger {
+	return rt.ledger
+}
+
+// Use appends global middlewares, which wrap every advance and inspect
+// handler, including the rejection of requests that have no handler.
+func (rt *Router) Use(mws ...Middleware) {
+	rt.middlewares = append(rt.middlewares, mws...)
+}
+
+// HandleSender registers h for every advance whose msg_sender is sender,
+// replacing any handler previously registered for the same address.
+func (rt *Router) HandleSender(sender common.Address, h Handler, mws ...Middleware) {
+	rt.senders[sender] = chain(h, mws)
+}
+
+// HandleSelector registers h for advances whose payload starts with the
+// 4-byte selector and whose msg_sender has no sender handler.
+func (rt *Router) HandleSelector(selector uint32, h Handler, mws ...Middleware) {
+	rt.methods[selector] = chain(h, mws)
+}
+
+// HandleFallback registers h for advances that match neither a sender nor a
+// method handler. Without a fallback such advances are rejected.
+func (rt *Router) HandleFallback(h Handler, mws ...Middleware) {
+	rt.fallback = chain(h, mws)
+}
+
+// HandleInspect registers h for inspect requests that match no inspect route.
+func (rt *Router) HandleInspect(h Handler, mws ...Middleware) {
+	rt.inspect = chain(h, mws)
+}
+
+func (rt *Router) HandleEtherDeposit(h EtherDepositHandler, mws ...Middleware) {
+	rt.HandleSender(EtherPortal, func(ctx *Context) error {
+		deposit, err := parser.DecodeEtherDeposit(ctx.Advance.Payload)
+		if err != nil {
+			return err
+		}
+		return h(ctx, deposit)
+	}, mws...)
+}
+
+func (rt *Router) HandleERC20Deposit(h ERC20DepositHandler, mws ...Middleware) {
+	rt.HandleSender(ERC20Portal, func(ctx *Context) error {
+		deposit, err := parser.DecodeERC20Deposit(ctx.Advance.Payload)
+		if err != nil {
+			return err
+		}
+		return h(ctx, deposit)
+	}, mws...)
+}
+
+func (rt *Router) HandleERC721Deposit(h ERC721DepositHandler, mws ...Middleware) {
+	rt.HandleSender(ERC721Portal, func(ctx *Context) error {
+		deposit, err := parser.DecodeERC721Deposit(ctx.Advance.Payload)
+		if err != nil {
+			return err
+		}
+		return h(ctx, deposit)
+	}, mws...)
+}
+
+func (rt *Router) HandleERC1155SingleDeposit(h ERC1155SingleDepositHandler, mws ...Middleware) {
+	rt.HandleSender(ERC1155SinglePortal, func(ctx *Context) error {
+		deposit, err := parser.DecodeERC1155SingleDeposit(ctx.Advance.Payload)
+		if err != nil {
+			return err
+		}
+		return h(ctx, deposit)
+	}, mws...)
+}
+
+func (rt *Router) HandleERC1155BatchDeposit(h ERC1155BatchDepositHandler, mws ...Middleware) {
+	rt.HandleSender(ERC1155BatchPortal, func(ctx *Context) error {
+		deposit, err := parser.DecodeERC1155BatchDeposit(ctx.Advance.Payload)
+		if err != nil {
+			return err
+		}
+		return h(ctx, deposit)
+	}, mws...)
+}
+
+// Run owns the rollup Finish loop: it accepts or rejects the previous request
+// based on the result of its handler and dispatches the next one. It only
+// returns when Finish fails, or with nil when Finish reports the end of the
+// input with an io.EOF error, as the mock rollup does once its input is
+// closed.
+func (rt *Router) Run() error {
+	accept := true
+	for {
+		reqType, _, err := rt.rollup.Finish(accept)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch reqType {
+		case rollup.RequestTypeAdvance:
+			accept = rt.handleAdvance()
+		case rollup.RequestTypeInspect:
+			accept = rt.handleInspect()
+		default:
+			accept = false
+		}
+	}
+}
+
+func (rt *Router) handleAdvance() bool {
+	advance, err := rt.rollup.ReadAdvanceState()
+	if err != nil {
+		log.Printf("[router] failed to read advance: %v", err)
+		return false
+	}
+
+	rt.last = advance.Metadata
+	rt.advanced = true
+
+	ctx := NewAdvanceContext(rt.rollup, rt.ledger, advance)
+	if err := rt.serve(rt.route(advance), ctx); err != nil {
+		log.Printf("[router] rejected advance %d from %s: %v", advance.Index, advance.MsgSender.Hex(), err)
+		return false
+	}
+	return true
+}
+
+func (rt *Router) route(advance *rollup.Advance) Handler {
+	if h, ok := rt.senders[advance.MsgSender]; ok {
+		return h
+	}
+	if len(advance.Payload) >= 4 {
+		if h, ok := rt.methods[binary.BigEndian.Uint32(advance.Payload[0:4])]; ok {
+			return h
+		}
+	}
+	return rt.fallback
+}
+
+func (rt *Router) handleInspect() bool {
+	inspect, err := rt.rollup.ReadInspectState()
+	if err != nil {
+		log.Printf("[router] failed to read inspect: %v", err)
+		return false
+	}
+
+	ctx := NewInspectContext(rt.rollup, rt.ledger, inspect)
+	h, params := rt.routeInspect(inspect)
+	ctx.Params = params
+	if err := rt.serve(h, ctx); err != nil {
+		log.Printf("[router] rejected inspect: %v", err)
+		return false
+	}
+	return true
+}
+
+// serve runs h behind the global middlewares. A panic anywhere in the chain
+// rejects the request and is reported instead of halting the machine.
+func (rt *Router) serve(h Handler, ctx *Context) (err error) {
+	if h == nil {
+		h = noHandler
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = recoverPanic(ctx, p)
+		}
+	}()
+	return chain(h, rt.middlewares)(ctx)
+}
+
+func noHandler(ctx *Context) error {
+	return ErrNoHandler
+}
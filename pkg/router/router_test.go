@@ -0,0 +1,200 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+var (
+	alice = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	bob   = common.HexToAddress("0x00000000000000000000000000000000000000a2")
+)
+
+// newRouter creates a router over a mock rollup and ledger.
+func newRouter(t *testing.T) *Router {
+	t.Helper()
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ledger.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(r, l)
+}
+
+// mock returns the mock rollup under rt, which tests queue requests on.
+func mock(rt *Router) *rollup.Rollup {
+	return rt.rollup.(*rollup.Rollup)
+}
+
+// step serves the next queued request as one iteration of Run does and
+// returns whether it was accepted.
+func step(t *testing.T, rt *Router) bool {
+	t.Helper()
+	reqType, _, err := rt.rollup.Finish(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reqType == rollup.RequestTypeInspect {
+		return rt.handleInspect()
+	}
+	return rt.handleAdvance()
+}
+
+func advance(t *testing.T, rt *Router, sender common.Address, payload []byte) bool {
+	t.Helper()
+	mock(rt).Advance(&rollup.Advance{Metadata: rollup.Metadata{MsgSender: sender}, Payload: payload})
+	return step(t, rt)
+}
+
+func inspect(t *testing.T, rt *Router, payload []byte) bool {
+	t.Helper()
+	mock(rt).Inspect(&rollup.Inspect{Payload: payload})
+	return step(t, rt)
+}
+
+func erc20Deposit(token, sender common.Address, amount *big.Int) []byte {
+	payload := append(token.Bytes(), sender.Bytes()...)
+	return append(payload, common.LeftPadBytes(amount.Bytes(), 32)...)
+}
+
+// record returns a handler that sets *ran to name.
+func record(ran *string, name string) Handler {
+	return func(ctx *Context) error {
+		*ran = name
+		return nil
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	rt := newRouter(t)
+
+	var ran string
+	rt.HandleSender(bob, record(&ran, "sender"))
+	rt.HandleFallback(record(&ran, "fallback"))
+	rt.HandleERC20Deposit(func(ctx *Context, d *parser.ERC20Deposit) error {
+		ran = "deposit of " + d.Amount.String()
+		return nil
+	})
+	rt.HandleInspect(func(ctx *Context) error {
+		ran = "inspect " + string(ctx.Payload())
+		return nil
+	})
+
+	tests := []struct {
+		sender  common.Address
+		payload []byte
+		want    string
+	}{
+		{bob, []byte("x"), "sender"},
+		{alice, []byte("x"), "fallback"},
+		{alice, nil, "fallback"},
+		{ERC20Portal, erc20Deposit(bob, alice, big.NewInt(7)), "deposit of 7"},
+	}
+	for _, tc := range tests {
+		ran = ""
+		if !advance(t, rt, tc.sender, tc.payload) {
+			t.Fatalf("advance from %s rejected", tc.sender.Hex())
+		}
+		if ran != tc.want {
+			t.Fatalf("advance from %s: ran %q, want %q", tc.sender.Hex(), ran, tc.want)
+		}
+	}
+
+	ran = ""
+	if advance(t, rt, ERC20Portal, erc20Deposit(bob, alice, big.NewInt(7))[:10]) || ran != "" {
+		t.Fatalf("expected a malformed deposit to be rejected before its handler, ran %q", ran)
+	}
+
+	if !inspect(t, rt, []byte("hello")) || ran != "inspect hello" {
+		t.Fatalf("inspect: ran %q", ran)
+	}
+}
+
+func TestUnhandledRequestsAreRejected(t *testing.T) {
+	rt := newRouter(t)
+
+	if advance(t, rt, alice, []byte("x")) {
+		t.Fatal("expected an advance without handler to be rejected")
+	}
+	if inspect(t, rt, []byte("x")) {
+		t.Fatal("expected an inspect without handler to be rejected")
+	}
+}
+
+func TestRunEndsWithTheInput(t *testing.T) {
+	rt := newRouter(t)
+
+	var payloads []string
+	rt.HandleFallback(func(ctx *Context) error {
+		payloads = append(payloads, string(ctx.Payload()))
+		return nil
+	})
+	r := mock(rt)
+	for _, p := range []string{"a", "b", "c"} {
+		r.Advance(&rollup.Advance{Metadata: rollup.Metadata{MsgSender: alice}, Payload: []byte(p)})
+	}
+	r.CloseInput()
+
+	if err := rt.Run(); err != nil {
+		t.Fatalf("expected Run to return cleanly, got %v", err)
+	}
+	if len(payloads) != 3 || payloads[0] != "a" || payloads[2] != "c" {
+		t.Fatalf("expected the queued inputs to be served in order, got %q", payloads)
+	}
+	if accepted := r.Accepted(); len(accepted) != 3 {
+		t.Fatalf("expected 3 finished inputs, got %d", len(accepted))
+	}
+
+	_, _, err := r.Finish(true)
+	if !errors.Is(err, rollup.ErrEndOfInput) || !errors.Is(err, io.EOF) {
+		t.Fatalf("expected %v, got %v", rollup.ErrEndOfInput, err)
+	}
+}
+
+// counting wraps a rollup and counts the requests it finishes.
+type counting struct {
+	rollup.Interface
+	finished int
+}
+
+func (c *counting) Finish(accept bool) (rollup.RequestType, uint32, error) {
+	c.finished++
+	return c.Interface.Finish(accept)
+}
+
+func TestRunOverAWrapper(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &counting{Interface: r}
+	rt := New(c, nil)
+
+	var ran string
+	rt.HandleFallback(record(&ran, "fallback"))
+	r.Advance(&rollup.Advance{Metadata: rollup.Metadata{MsgSender: alice}, Payload: []byte("x")})
+	r.Inspect(&rollup.Inspect{Payload: []byte("x")})
+	r.CloseInput()
+
+	if err := rt.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if ran != "fallback" || c.finished != 3 {
+		t.Fatalf("expected the wrapper to drive both requests, ran %q with %d calls to Finish", ran, c.finished)
+	}
+	if accepted := r.Accepted(); len(accepted) != 2 || !accepted[0] || accepted[1] {
+		t.Fatalf("expected the advance accepted and the unhandled inspect rejected, got %v", accepted)
+	}
+}
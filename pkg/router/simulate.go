@@ -0,0 +1,125 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+// Simulation is the report produced by a simulated advance.
+type Simulation struct {
+	Accepted             bool                  `json:"accepted"`
+	Error                string                `json:"error,omitempty"`
+	Vouchers             []SimulatedVoucher    `json:"vouchers"`
+	DelegateCallVouchers []SimulatedDelegation `json:"delegateCallVouchers"`
+	Notices              []hexutil.Bytes       `json:"notices"`
+	Reports              []hexutil.Bytes       `json:"reports"`
+}
+
+type SimulatedVoucher struct {
+	Destination common.Address `json:"destination"`
+	Value       *hexutil.Big   `json:"value"`
+	Payload     hexutil.Bytes  `json:"payload"`
+}
+
+type SimulatedDelegation struct {
+	Destination common.Address `json:"destination"`
+	Payload     hexutil.Bytes  `json:"payload"`
+}
+
+// HandleSimulation registers the inspect route
+//
+//	simulate/{sender}/{payload}
+//
+// which runs the advance handler for the hex-encoded payload as if it had
+// been sent by sender, against a fork of the router's ledger, and answers with
+// a single JSON report holding a Simulation: whether the advance would be
+// accepted and the outputs it would produce. A rejected advance produces no
+// vouchers or notices, only reports. Nothing it does is kept.
+//
+// The simulated advance reuses the chain id, application address, block
+// number and timestamp of the last advance processed, with the next input
+// index.
+func (rt *Router) HandleSimulation(mws ...Middleware) error {
+	if rt.ledger == nil {
+		return ErrNoLedger
+	}
+	rt.HandleInspectRoute("simulate/{sender}/{payload}", rt.handleSimulation, mws...)
+	return nil
+}
+
+func (rt *Router) handleSimulation(ctx *Context) error {
+	sender, err := ctx.Params.Address("sender")
+	if err != nil {
+		return err
+	}
+	raw, err := ctx.Params.Get("payload")
+	if err != nil {
+		return err
+	}
+	payload, err := hexutil.Decode(raw)
+	if err != nil {
+		return fmt.Errorf("%w: payload: %v", ErrInvalidParam, err)
+	}
+
+	fork, err := ctx.Ledger.Fork()
+	if err != nil {
+		return err
+	}
+
+	metadata := rt.last
+	if rt.advanced {
+		metadata.Index++
+	}
+	metadata.MsgSender = sender
+	advance := &rollup.Advance{Metadata: metadata, Payload: payload}
+
+	capture := &rollup.Capture{}
+	simulated := NewAdvanceContext(capture, fork, advance)
+	simErr := rt.serve(rt.route(advance), simulated)
+
+	report, err := json.Marshal(newSimulation(capture, simErr))
+	if err != nil {
+		return err
+	}
+	return ctx.Report(report)
+}
+
+func newSimulation(capture *rollup.Capture, err error) *Simulation {
+	s := &Simulation{
+		Accepted:             err == nil,
+		Vouchers:             []SimulatedVoucher{},
+		DelegateCallVouchers: []SimulatedDelegation{},
+		Notices:              []hexutil.Bytes{},
+		Reports:              []hexutil.Bytes{},
+	}
+	for _, r := range capture.Reports {
+		s.Reports = append(s.Reports, r.Payload)
+	}
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+
+	for _, v := range capture.Vouchers {
+		s.Vouchers = append(s.Vouchers, SimulatedVoucher{
+			Destination: v.Destination,
+			Value:       (*hexutil.Big)(new(big.Int).Set(v.Value)),
+			Payload:     v.Payload,
+		})
+	}
+	for _, v := range capture.DelegateCallVouchers {
+		s.DelegateCallVouchers = append(s.DelegateCallVouchers, SimulatedDelegation{
+			Destination: v.Destination,
+			Payload:     v.Payload,
+		})
+	}
+	for _, n := range capture.Notices {
+		s.Notices = append(s.Notices, n.Payload)
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+//go:build !riscv64
+
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+)
+
+// deposit credits one unit of ether to the sender and announces it with a
+// notice and a voucher.
+func deposit(ctx *Context) error {
+	assetID, err := ctx.Ledger.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	accountID, err := ctx.Ledger.RetrieveAccountByAddress(ctx.Sender(), ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Ledger.Deposit(assetID, accountID, big.NewInt(1)); err != nil {
+		return err
+	}
+	if _, err := ctx.Notice([]byte("deposited")); err != nil {
+		return err
+	}
+	_, err = ctx.Voucher(parser.EncodeEtherVoucher(ctx.Sender(), big.NewInt(1)))
+	return err
+}
+
+// simulate inspects a simulation of payload sent by sender and decodes its
+// report.
+func simulate(t *testing.T, rt *Router, sender common.Address, payload []byte) *Simulation {
+	t.Helper()
+
+	before := len(mock(rt).Reports())
+	if !inspect(t, rt, []byte("simulate/"+sender.Hex()+"/"+hexutil.Encode(payload))) {
+		t.Fatal("expected the simulation to be accepted")
+	}
+	reports := mock(rt).Reports()[before:]
+	if len(reports) != 1 {
+		t.Fatalf("expected a single report, got %d", len(reports))
+	}
+	var s Simulation
+	if err := json.Unmarshal(reports[0].Payload, &s); err != nil {
+		t.Fatal(err)
+	}
+	return &s
+}
+
+func TestHandleSimulation(t *testing.T) {
+	rt := newRouter(t)
+	rt.HandleFallback(deposit)
+	if err := rt.HandleSimulation(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := simulate(t, rt, alice, []byte("x"))
+	if !s.Accepted || s.Error != "" {
+		t.Fatalf("expected an accepted simulation, got %+v", s)
+	}
+	if len(s.Notices) != 1 || string(s.Notices[0]) != "deposited" {
+		t.Fatalf("unexpected notices %q", s.Notices)
+	}
+	if len(s.Vouchers) != 1 || s.Vouchers[0].Destination != alice || s.Vouchers[0].Value.ToInt().Int64() != 1 {
+		t.Fatalf("unexpected vouchers %+v", s.Vouchers)
+	}
+
+	// The simulated deposit never reaches the router's ledger.
+	if _, err := rt.ledger.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFind); !errors.Is(err, ledger.ErrAssetNotFound) {
+		t.Fatalf("expected the ledger to be unchanged, got %v", err)
+	}
+
+	for _, payload := range []string{"simulate/not-an-address/0x00", "simulate/" + alice.Hex() + "/not-hex"} {
+		if inspect(t, rt, []byte(payload)) {
+			t.Fatalf("%s: expected invalid parameters to be rejected", payload)
+		}
+	}
+}
+
+func TestHandleSimulationOfARejectedAdvance(t *testing.T) {
+	rt := newRouter(t)
+	rt.HandleFallback(func(ctx *Context) error {
+		if err := deposit(ctx); err != nil {
+			return err
+		}
+		if string(ctx.Payload()) == "reject" {
+			return ctx.Reject("rejected")
+		}
+		return nil
+	})
+	if err := rt.HandleSimulation(); err != nil {
+		t.Fatal(err)
+	}
+	if !advance(t, rt, alice, []byte("x")) {
+		t.Fatal("expected the deposit to be accepted")
+	}
+
+	if s := simulate(t, rt, alice, []byte("x")); !s.Accepted || len(s.Notices) != 1 || len(s.Vouchers) != 1 {
+		t.Fatalf("expected an accepted simulation with its outputs, got %+v", s)
+	}
+
+	// The outputs of a rejected advance are discarded, its reports are not.
+	s := simulate(t, rt, alice, []byte("reject"))
+	if s.Accepted || !strings.Contains(s.Error, ErrRejected.Error()) {
+		t.Fatalf("expected a rejected simulation, got %+v", s)
+	}
+	if len(s.Notices) != 0 || len(s.Vouchers) != 0 || len(s.DelegateCallVouchers) != 0 {
+		t.Fatalf("expected no outputs, got %+v", s)
+	}
+	if len(s.Reports) != 1 || string(s.Reports[0]) != "rejected" {
+		t.Fatalf("expected the rejection to be reported, got %q", s.Reports)
+	}
+
+	// Neither simulation reaches the router's ledger nor its outputs, which
+	// hold the real deposit alone.
+	assetID, err := rt.ledger.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if supply, err := rt.ledger.GetTotalSupply(assetID); err != nil || supply.Int64() != 1 {
+		t.Fatalf("expected the ledger to hold the real deposit alone, got %v, %v", supply, err)
+	}
+	if outputs := mock(rt).Outputs(); len(outputs) != 2 {
+		t.Fatalf("expected the 2 outputs of the real deposit, got %d", len(outputs))
+	}
+}
+
+func TestHandleSimulationRequiresLedger(t *testing.T) {
+	rt := New(newRouter(t).rollup, nil)
+	if err := rt.HandleSimulation(); !errors.Is(err, ErrNoLedger) {
+		t.Fatalf("expected %v, got %v", ErrNoLedger, err)
+	}
+}
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+)
+
+// Portals
+
+var (
+	EtherPortal         = common.HexToAddress("0xFfdbe43d4c855BF7e0f105c400A50857f53AB044")
+	ERC20Portal         = common.HexToAddress("0x9C21AEb2093C32DDbC53eEF24B873BDCd1aDa1DB")
+	ERC721Portal        = common.HexToAddress("0x237F8DD094C0e47f4236f12b4Fa01d6Dae89fb87")
+	ERC1155SinglePortal = common.HexToAddress("0x7CFB0193Ca87eB6e48056885E026552c3A941FC4")
+	ERC1155BatchPortal  = common.HexToAddress("0xedB53860A6B52bbb7561Ad596416ee9965B055Aa")
+)
+
+// Handlers
+
+type Handler func(ctx *Context) error
+
+type Middleware func(Handler) Handler
+
+type EtherDepositHandler func(ctx *Context, deposit *parser.EtherDeposit) error
+
+type ERC20DepositHandler func(ctx *Context, deposit *parser.ERC20Deposit) error
+
+type ERC721DepositHandler func(ctx *Context, deposit *parser.ERC721Deposit) error
+
+type ERC1155SingleDepositHandler func(ctx *Context, deposit *parser.ERC1155SingleDeposit) error
+
+type ERC1155BatchDepositHandler func(ctx *Context, deposit *parser.ERC1155BatchDeposit) error
@@ -0,0 +1,11 @@
+//go:build !riscv64
+
+package tester
+
+import "errors"
+
+var (
+	ErrTimeout         = errors.New("timed out waiting for the app")
+	ErrAppExited       = errors.New("app exited")
+	ErrInvalidScenario = errors.New("invalid scenario")
+)
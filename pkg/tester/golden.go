@@ -0,0 +1,164 @@
+//go:build !riscv64
+
+package tester
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var update = flag.Bool("update-golden", false, "rewrite the golden files of tester.ExpectGolden")
+
+// ExpectGolden compares every request sent so far and its outcome with the
+// golden file testdata/name.golden, failing the test on any difference. Run
+// the tests with -update-golden to write the file instead.
+//
+// The file is meant to be read and reviewed: one block per request, with its
+// outcome, exception, outputs in index order and reports, and payloads in hex
+// followed by their text when printable.
+func (tt *Tester) ExpectGolden(name string) {
+	tt.t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	got := tt.Transcript()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tt.t.Fatalf("tester: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			tt.t.Fatalf("tester: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tt.t.Fatalf("tester: %v (run with -update-golden to create it)", err)
+	}
+	if line, ok := firstDiff(string(want), got); !ok {
+		tt.t.Errorf("tester: %s differs at line %d:\n\twant: %s\n\tgot:  %s\n(run with -update-golden to accept the changes)",
+			path, line.number, line.want, line.got)
+	}
+}
+
+// Transcript returns the golden file contents for the requests sent so far.
+func (tt *Tester) Transcript() string {
+	var b strings.Builder
+	for _, res := range tt.results {
+		res.transcript(&b)
+	}
+	return b.String()
+}
+
+func (res *Result) transcript(b *strings.Builder) {
+	if res.Advance != nil {
+		fmt.Fprintf(b, "advance %d from %s: %s\n", res.Advance.Index, res.Advance.MsgSender.Hex(), outcome(res.Accepted))
+		writePayload(b, "\tinput", res.Advance.Payload)
+	} else {
+		fmt.Fprintf(b, "inspect: %s\n", outcome(res.Accepted))
+		writePayload(b, "\tinput", res.Inspect.Payload)
+	}
+	if res.Exception != nil {
+		writePayload(b, "\texception", res.Exception)
+	}
+
+	type output struct {
+		index uint64
+		write func()
+	}
+	var outputs []output
+	for _, v := range res.Vouchers {
+		v := v
+		outputs = append(outputs, output{v.Index, func() {
+			fmt.Fprintf(b, "\tvoucher %d to %s value %s\n", v.Index, v.Destination.Hex(), v.Value)
+			writePayload(b, "\t\tpayload", v.Payload)
+		}})
+	}
+	for _, v := range res.DelegateCallVouchers {
+		v := v
+		outputs = append(outputs, output{v.Index, func() {
+			fmt.Fprintf(b, "\tdelegate call voucher %d to %s\n", v.Index, v.Destination.Hex())
+			writePayload(b, "\t\tpayload", v.Payload)
+		}})
+	}
+	for _, n := range res.Notices {
+		n := n
+		outputs = append(outputs, output{n.Index, func() {
+			fmt.Fprintf(b, "\tnotice %d\n", n.Index)
+			writePayload(b, "\t\tpayload", n.Payload)
+		}})
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].index < outputs[j].index })
+	for _, o := range outputs {
+		o.write()
+	}
+
+	for _, r := range res.Reports {
+		writePayload(b, "\treport", r.Payload)
+	}
+	b.WriteByte('\n')
+}
+
+func outcome(accepted bool) string {
+	if accepted {
+		return "accepted"
+	}
+	return "rejected"
+}
+
+// writePayload writes payload in hex, followed by its quoted text when it is
+// printable.
+func writePayload(b *strings.Builder, label string, payload []byte) {
+	fmt.Fprintf(b, "%s %s", label, hexutil.Encode(payload))
+	if printable(payload) {
+		fmt.Fprintf(b, " %q", payload)
+	}
+	b.WriteByte('\n')
+}
+
+func printable(payload []byte) bool {
+	if len(payload) == 0 || !utf8.Valid(payload) {
+		return false
+	}
+	for _, r := range string(payload) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type lineDiff struct {
+	number    int
+	want, got string
+}
+
+// firstDiff finds the first line where want and got differ.
+func firstDiff(want, got string) (lineDiff, bool) {
+	if want == got {
+		return lineDiff{}, true
+	}
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; ; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return lineDiff{number: i + 1, want: w, got: g}, false
+		}
+	}
+}
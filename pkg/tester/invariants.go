@@ -0,0 +1,200 @@
+//go:build !riscv64
+
+package tester
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+)
+
+var (
+	portals = map[common.Address]bool{
+		router.EtherPortal:         true,
+		router.ERC20Portal:         true,
+		router.ERC721Portal:        true,
+		router.ERC1155SinglePortal: true,
+		router.ERC1155BatchPortal:  true,
+	}
+
+	withdrawalSelectors = map[uint32]bool{
+		parser.SelectorWithdrawEther:         true,
+		parser.SelectorWithdrawERC20:         true,
+		parser.SelectorWithdrawERC721:        true,
+		parser.SelectorWithdrawERC1155Single: true,
+		parser.SelectorWithdrawERC1155Batch:  true,
+	}
+
+	metaTxSelector  = router.Selector(router.MetaTxSignature)
+	metaTxArguments = func() abi.Arguments {
+		var arguments abi.Arguments
+		for _, t := range []string{"address", "uint256", "bytes", "bytes"} {
+			typ, _ := abi.NewType(t, "", nil)
+			arguments = append(arguments, abi.Argument{Type: typ})
+		}
+		return arguments
+	}()
+)
+
+// invariants holds what the ledger checks need to remember between requests.
+type invariants struct {
+	// exempt are the application tokens whose supply may change on any
+	// input.
+	exempt map[common.Address]bool
+	// erc721 are the assets deposited through the ERC721 portal.
+	erc721 map[ledger.AssetID]bool
+	// supplies are the supplies after the previous request.
+	supplies map[ledger.AssetID]*big.Int
+}
+
+// CheckLedger makes the Tester verify the ledger after every following
+// request, failing the test on the first violation of these invariants:
+//
+//   - the total supply of each asset is the sum of its balances;
+//   - no balance or supply is negative;
+//   - an asset deposited through the ERC721 portal has a supply of at most 1;
+//   - supplies only change on deposits, advances from a portal, and
+//     withdrawals, advances starting with one of the wallet withdrawal
+//     selectors, sent directly or relayed as a meta-transaction.
+//
+// The assets under router.ReservedToken may change supply on any input, as
+// may the application tokens in exempt.
+func (tt *Tester) CheckLedger(exempt ...common.Address) *Tester {
+	tt.t.Helper()
+
+	inv := &invariants{
+		exempt: make(map[common.Address]bool, len(exempt)),
+		erc721: make(map[ledger.AssetID]bool),
+	}
+	for _, token := range exempt {
+		inv.exempt[token] = true
+	}
+
+	supplies, err := supplies(tt.ledger)
+	if err != nil {
+		tt.t.Fatalf("tester: %v", err)
+	}
+	inv.supplies = supplies
+	tt.invariants = inv
+	return tt
+}
+
+// checkLedger verifies the ledger after res, if CheckLedger was called.
+func (tt *Tester) checkLedger(res *Result) {
+	tt.t.Helper()
+
+	if tt.invariants == nil {
+		return
+	}
+	if err := tt.invariants.check(tt.ledger, res); err != nil {
+		tt.t.Fatalf("tester: %s: ledger invariant violated: %v", res, err)
+	}
+}
+
+func (inv *invariants) check(l *ledger.Ledger, res *Result) error {
+	if res.Advance != nil && res.Accepted && res.Advance.MsgSender == router.ERC721Portal {
+		if d, err := parser.DecodeERC721Deposit(res.Advance.Payload); err == nil {
+			assetID, err := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+			if err == nil {
+				inv.erc721[assetID] = true
+			}
+		}
+	}
+
+	assets, err := l.Assets()
+	if err != nil {
+		return err
+	}
+
+	current := make(map[ledger.AssetID]*big.Int, len(assets))
+	for _, asset := range assets {
+		supply, err := l.GetTotalSupply(asset.ID)
+		if err != nil {
+			return err
+		}
+		current[asset.ID] = supply
+
+		balances, err := l.Balances(asset.ID)
+		if err != nil {
+			return err
+		}
+		sum := new(big.Int)
+		for accountID, balance := range balances {
+			if balance.Sign() < 0 {
+				return fmt.Errorf("asset %d: account %d has a negative balance %s", asset.ID, accountID, balance)
+			}
+			sum.Add(sum, balance)
+		}
+
+		if supply.Sign() < 0 {
+			return fmt.Errorf("asset %d: negative supply %s", asset.ID, supply)
+		}
+		if supply.Cmp(sum) != 0 {
+			return fmt.Errorf("asset %d: supply %s differs from the sum of balances %s", asset.ID, supply, sum)
+		}
+		if inv.erc721[asset.ID] && supply.Cmp(big.NewInt(1)) > 0 {
+			return fmt.Errorf("asset %d: ERC721 %s #%s has a supply of %s", asset.ID, asset.Token.Hex(), asset.TokenID, supply)
+		}
+
+		previous, ok := inv.supplies[asset.ID]
+		if !ok {
+			previous = new(big.Int)
+		}
+		if supply.Cmp(previous) != 0 && asset.Token != router.ReservedToken && !inv.exempt[asset.Token] && !changesSupply(res) {
+			return fmt.Errorf("asset %d: supply changed from %s to %s outside of a deposit or withdrawal", asset.ID, previous, supply)
+		}
+	}
+
+	inv.supplies = current
+	return nil
+}
+
+// changesSupply reports whether res is a deposit or a withdrawal.
+func changesSupply(res *Result) bool {
+	if res.Advance == nil {
+		return false
+	}
+	if portals[res.Advance.MsgSender] {
+		return true
+	}
+	return isWithdrawal(res.Advance.Payload)
+}
+
+// isWithdrawal classifies payload by the call it makes, which for a
+// meta-transaction is the relayed one.
+func isWithdrawal(payload []byte) bool {
+	if len(payload) < 4 {
+		return false
+	}
+	selector := binary.BigEndian.Uint32(payload[:4])
+	if selector != metaTxSelector {
+		return withdrawalSelectors[selector]
+	}
+
+	values, err := metaTxArguments.Unpack(payload[4:])
+	if err != nil {
+		return false
+	}
+	data, _ := values[2].([]byte)
+	return len(data) >= 4 && withdrawalSelectors[binary.BigEndian.Uint32(data[:4])]
+}
+
+func supplies(l *ledger.Ledger) (map[ledger.AssetID]*big.Int, error) {
+	assets, err := l.Assets()
+	if err != nil {
+		return nil, err
+	}
+	supplies := make(map[ledger.AssetID]*big.Int, len(assets))
+	for _, asset := range assets {
+		if supplies[asset.ID], err = l.GetTotalSupply(asset.ID); err != nil {
+			return nil, err
+		}
+	}
+	return supplies, nil
+}
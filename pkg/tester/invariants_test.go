@@ -0,0 +1,115 @@
+//go:build !riscv64
+
+package tester_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/henriquemarlon/rollingopher/pkg/access"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+	"github.com/henriquemarlon/rollingopher/pkg/tester"
+	"github.com/henriquemarlon/rollingopher/pkg/wallet"
+)
+
+func TestCheckLedger(t *testing.T) {
+	domain := router.EIP712Domain{Name: "test", Version: "1"}
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000a0")
+	relayer := common.HexToAddress("0x00000000000000000000000000000000000000b0")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	key, err := crypto.ToECDSA(common.LeftPadBytes([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := crypto.PubkeyToAddress(key.PublicKey)
+
+	rec := &recorder{TB: t}
+	tt := tester.New(rec, func(r rollup.Interface, l *ledger.Ledger) error {
+		acl, err := access.New(l, owner)
+		if err != nil {
+			return err
+		}
+		rt := router.New(r, l)
+		if err := wallet.New().Register(rt); err != nil {
+			return err
+		}
+		if err := acl.Register(rt); err != nil {
+			return err
+		}
+		if err := rt.HandleMetaTransactions(domain); err != nil {
+			return err
+		}
+		// mint() creates tokens out of thin air, breaking the invariants.
+		rt.HandleSelector(router.Selector("mint()"), func(ctx *router.Context) error {
+			assetID, err := ctx.Ledger.RetrieveAsset(token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFindOrCreate)
+			if err != nil {
+				return err
+			}
+			accountID, err := ctx.Ledger.RetrieveAccountByAddress(ctx.Sender(), ledger.RetrieveOperationFindOrCreate)
+			if err != nil {
+				return err
+			}
+			return ctx.Ledger.Deposit(assetID, accountID, big.NewInt(1))
+		})
+		return rt.Run()
+	}).CheckLedger()
+
+	tt.Advance(router.ERC20Portal, parser.EncodeERC20Deposit(&parser.ERC20Deposit{Token: token, Sender: alice, Amount: big.NewInt(10)})).ExpectAccepted()
+
+	tt.Advance(alice, parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: token, Amount: big.NewInt(4)})).ExpectAccepted().ExpectOutputs(1, 0, 0)
+
+	// relay signs data as alice and wraps it in a meta-transaction.
+	relay := func(nonce int64, data []byte) []byte {
+		hash := router.MetaTxHash(domain, tt.ChainID, tt.AppContract, alice, big.NewInt(nonce), data)
+		signature, err := crypto.Sign(hash.Bytes(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := router.EncodeCall(router.MetaTxSignature, alice, big.NewInt(nonce), data, signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+
+	// A transfer relayed as a meta-transaction, which bumps alice's reserved nonce,
+	// and a role grant only change exempt supplies.
+	transfer := parser.EncodeERC20Transfer(&parser.ERC20Transfer{Token: token, Receiver: common.BytesToHash(relayer.Bytes()), Amount: big.NewInt(1)})
+	tt.Advance(relayer, relay(0, transfer)).ExpectAccepted()
+	grant, err := router.EncodeCall("grantRole(string,address)", "minter", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.Advance(owner, grant).ExpectAccepted()
+
+	// A withdrawal relayed as a meta-transaction changes the supply as a
+	// direct one does.
+	withdrawal := parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: token, Amount: big.NewInt(2)})
+	tt.Advance(relayer, relay(1, withdrawal)).ExpectAccepted().ExpectOutputs(1, 0, 0)
+
+	if len(rec.errors) != 0 {
+		t.Fatalf("unexpected failures: %q", rec.errors)
+	}
+
+	mint, err := router.EncodeCall("mint()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The violation ends the goroutine sending the request, as t.Fatalf does.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tt.Advance(alice, mint).ExpectAccepted()
+	}()
+	<-done
+
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "outside of a deposit or withdrawal") {
+		t.Fatalf("expected a supply violation, got %q", rec.errors)
+	}
+}
@@ -0,0 +1,145 @@
+//go:build !riscv64
+
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+// Result holds a request sent by a Tester, whether the app accepted it and
+// the outputs it emitted while processing it. The Expect methods fail the test
+// on mismatch and return the Result for chaining.
+type Result struct {
+	rollup.Input
+
+	t testing.TB
+
+	// step names the scenario step the request comes from, if any.
+	step string
+}
+
+func (res *Result) ExpectAccepted() *Result {
+	res.t.Helper()
+	if !res.Accepted {
+		res.t.Errorf("%s: expected accepted, got rejected%s", res, res.reportsSuffix())
+	}
+	return res
+}
+
+func (res *Result) ExpectRejected() *Result {
+	res.t.Helper()
+	if res.Accepted {
+		res.t.Errorf("%s: expected rejected, got accepted", res)
+	}
+	return res
+}
+
+// ExpectException checks that the app raised an exception with payload.
+func (res *Result) ExpectException(payload []byte) *Result {
+	res.t.Helper()
+	if res.Exception == nil {
+		res.t.Errorf("%s: expected exception %s, got none", res, hexutil.Encode(payload))
+	} else if !bytes.Equal(res.Exception, payload) {
+		res.t.Errorf("%s: expected exception %s, got %s", res, hexutil.Encode(payload), hexutil.Encode(res.Exception))
+	}
+	return res
+}
+
+// ExpectNotice checks that one of the notices has the given payload.
+func (res *Result) ExpectNotice(payload []byte) *Result {
+	res.t.Helper()
+	for _, n := range res.Notices {
+		if bytes.Equal(n.Payload, payload) {
+			return res
+		}
+	}
+	res.t.Errorf("%s: no notice with payload %s among %d", res, hexutil.Encode(payload), len(res.Notices))
+	return res
+}
+
+// ExpectVoucher checks that one of the vouchers matches v, typically built
+// with the parser.Encode*Voucher functions. A nil value matches zero.
+func (res *Result) ExpectVoucher(v *parser.Voucher) *Result {
+	res.t.Helper()
+	value := orZero(v.Value)
+	for _, got := range res.Vouchers {
+		if got.Destination == v.Destination && orZero(got.Value).Cmp(value) == 0 && bytes.Equal(got.Payload, v.Payload) {
+			return res
+		}
+	}
+	res.t.Errorf("%s: no voucher to %s with value %s and payload %s among %d",
+		res, v.Destination.Hex(), value, hexutil.Encode(v.Payload), len(res.Vouchers))
+	return res
+}
+
+func (res *Result) ExpectDelegateCallVoucher(v *parser.DelegateCallVoucher) *Result {
+	res.t.Helper()
+	for _, got := range res.DelegateCallVouchers {
+		if got.Destination == v.Destination && bytes.Equal(got.Payload, v.Payload) {
+			return res
+		}
+	}
+	res.t.Errorf("%s: no delegate call voucher to %s with payload %s among %d",
+		res, v.Destination.Hex(), hexutil.Encode(v.Payload), len(res.DelegateCallVouchers))
+	return res
+}
+
+// ExpectReport checks that one of the reports has the given payload.
+func (res *Result) ExpectReport(payload []byte) *Result {
+	res.t.Helper()
+	for _, r := range res.Reports {
+		if bytes.Equal(r.Payload, payload) {
+			return res
+		}
+	}
+	res.t.Errorf("%s: no report with payload %s among %d", res, hexutil.Encode(payload), len(res.Reports))
+	return res
+}
+
+// ExpectOutputs checks the number of vouchers, delegate call vouchers and
+// notices, which are the outputs the node keeps.
+func (res *Result) ExpectOutputs(vouchers, delegateCallVouchers, notices int) *Result {
+	res.t.Helper()
+	if len(res.Vouchers) != vouchers || len(res.DelegateCallVouchers) != delegateCallVouchers || len(res.Notices) != notices {
+		res.t.Errorf("%s: expected %d vouchers, %d delegate call vouchers and %d notices, got %d, %d and %d",
+			res, vouchers, delegateCallVouchers, notices, len(res.Vouchers), len(res.DelegateCallVouchers), len(res.Notices))
+	}
+	return res
+}
+
+func (res *Result) String() string {
+	s := "inspect"
+	if res.Advance != nil {
+		s = "advance " + strconv.FormatUint(res.Advance.Index, 10)
+	}
+	if res.step != "" {
+		s = res.step + ": " + s
+	}
+	return s
+}
+
+// reportsSuffix lists the reports of a rejected request, which usually
+// explain why it was rejected.
+func (res *Result) reportsSuffix() string {
+	var b strings.Builder
+	for _, r := range res.Reports {
+		fmt.Fprintf(&b, "\n\treport: %q", r.Payload)
+	}
+	return b.String()
+}
+
+func orZero(n *big.Int) *big.Int {
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
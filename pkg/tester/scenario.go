@@ -0,0 +1,635 @@
+//go:build !riscv64
+
+package tester
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a sequence of requests and the outcomes expected from them,
+// written in JSON or YAML and replayed with Tester.Replay or RunScenarios:
+//
+//	name: withdraw more than deposited
+//	steps:
+//	  - advance:
+//	      input: {type: EtherDeposit, sender: "0x...", amount: 100}
+//	    expect: {accepted: true}
+//	  - advance:
+//	      sender: "0x..."
+//	      blockTimestamp: 1700000000
+//	      input: {type: EtherWithdrawal, amount: 1000}
+//	    expect:
+//	      accepted: false
+//	      reports: ["ether withdrawal: insufficient funds"]
+//	  - inspect:
+//	      input: {type: BalanceAccount, account: "0x..."}
+//	    expect:
+//	      reports: ["0x0000000000000000000000000000000000000000000000000000000000000064"]
+//
+// Inputs are named after parser.InputType and take the fields of the
+// matching parser struct in lower camel case. Strings starting with 0x are
+// hex and any other payload is taken as text. Amounts are decimal or hex
+// numbers, quoted or not.
+type Scenario struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single advance or inspect request of a Scenario.
+type Step struct {
+	Name    string       `json:"name"`
+	Advance *AdvanceStep `json:"advance"`
+	Inspect *InspectStep `json:"inspect"`
+	Expect  Expectation  `json:"expect"`
+}
+
+// AdvanceStep sends Input, or Payload when there is no Input. Deposits come
+// from their portal unless Sender is set. The block fields, once set, apply
+// to the following advances as well.
+type AdvanceStep struct {
+	Sender         *common.Address `json:"sender"`
+	BlockNumber    *uint64         `json:"blockNumber"`
+	BlockTimestamp *uint64         `json:"blockTimestamp"`
+	Input          *Input          `json:"input"`
+	Payload        Payload         `json:"payload"`
+}
+
+// InspectStep sends Input, one of the Balance and Supply types, as a ledger
+// query, or Payload when there is no Input.
+type InspectStep struct {
+	Input   *Input  `json:"input"`
+	Payload Payload `json:"payload"`
+}
+
+// Input is an input of one of the parser.InputType types, with the fields
+// it uses.
+type Input struct {
+	Type          string         `json:"type"`
+	Token         common.Address `json:"token"`
+	Sender        common.Address `json:"sender"`
+	Receiver      Account        `json:"receiver"`
+	Account       Account        `json:"account"`
+	Amount        *Amount        `json:"amount"`
+	TokenID       *Amount        `json:"tokenId"`
+	TokenIDs      []*Amount      `json:"tokenIds"`
+	Amounts       []*Amount      `json:"amounts"`
+	BaseLayerData Payload        `json:"baseLayerData"`
+	ExecLayerData Payload        `json:"execLayerData"`
+}
+
+// Expectation lists what the app must do with a request. Unset fields are
+// not checked, and listed outputs need only be among those emitted.
+type Expectation struct {
+	Accepted             *bool                `json:"accepted"`
+	Exception            Payload              `json:"exception"`
+	Vouchers             []VoucherExpectation `json:"vouchers"`
+	DelegateCallVouchers []VoucherExpectation `json:"delegateCallVouchers"`
+	Notices              []Payload            `json:"notices"`
+	Reports              []Payload            `json:"reports"`
+	Outputs              *OutputsExpectation  `json:"outputs"`
+}
+
+// VoucherExpectation is a voucher given by its destination, value and
+// payload, or, when Type names a withdrawal, the voucher that withdrawal
+// produces for Receiver.
+type VoucherExpectation struct {
+	Type        string         `json:"type"`
+	Destination common.Address `json:"destination"`
+	Value       *Amount        `json:"value"`
+	Payload     Payload        `json:"payload"`
+
+	Token    common.Address `json:"token"`
+	Receiver common.Address `json:"receiver"`
+	Amount   *Amount        `json:"amount"`
+	TokenID  *Amount        `json:"tokenId"`
+	TokenIDs []*Amount      `json:"tokenIds"`
+	Amounts  []*Amount      `json:"amounts"`
+}
+
+// OutputsExpectation is the exact number of each kind of output.
+type OutputsExpectation struct {
+	Vouchers             int `json:"vouchers"`
+	DelegateCallVouchers int `json:"delegateCallVouchers"`
+	Notices              int `json:"notices"`
+}
+
+// Payload is bytes written as 0x-prefixed hex or, otherwise, as text.
+type Payload []byte
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(s, "0x") {
+		*p = Payload(s)
+		return nil
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return err
+	}
+	*p = b
+	return nil
+}
+
+// Amount is an integer written as a decimal or 0x-prefixed hex number,
+// quoted or not, or in exponent notation such as 1e18.
+type Amount big.Int
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	if n, ok := new(big.Int).SetString(s, 0); ok {
+		*a = Amount(*n)
+		return nil
+	}
+	if f, _, err := big.ParseFloat(s, 10, 256, big.ToNearestEven); err == nil && f.IsInt() {
+		n, _ := f.Int(nil)
+		*a = Amount(*n)
+		return nil
+	}
+	return fmt.Errorf("invalid amount %s", data)
+}
+
+// Int returns a as a *big.Int, or nil when a is nil.
+func (a *Amount) Int() *big.Int {
+	if a == nil {
+		return nil
+	}
+	return (*big.Int)(a)
+}
+
+// Account is a 32-byte account ID, which may also be written as an address,
+// left-padded as wallet accounts are.
+type Account common.Hash
+
+func (a *Account) UnmarshalJSON(data []byte) error {
+	var b hexutil.Bytes
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	if len(b) != common.AddressLength && len(b) != common.HashLength {
+		return fmt.Errorf("invalid account %s: expected an address or a 32-byte ID", data)
+	}
+	*a = Account(common.BytesToHash(b))
+	return nil
+}
+
+// LoadScenario reads a scenario from a JSON or YAML file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return s, nil
+}
+
+// ParseScenario parses a scenario written in JSON or YAML. Unknown fields
+// are rejected, so that a misspelled expectation is not silently skipped, as
+// are inputs and vouchers missing an amount or token ID their type needs.
+func ParseScenario(data []byte) (*Scenario, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := yamlToJSON(&b, &node); err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(&b)
+	dec.DisallowUnknownFields()
+	var s Scenario
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	for i, step := range s.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", step.name(i), err)
+		}
+	}
+	return &s, nil
+}
+
+// yamlToJSON converts a YAML document to JSON, keeping the text of numbers
+// so that amounts beyond 64 bits are not rounded, and turning 0x-prefixed
+// ones into strings.
+func yamlToJSON(b *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			b.WriteString("null")
+			return nil
+		}
+		return yamlToJSON(b, n.Content[0])
+
+	case yaml.AliasNode:
+		return yamlToJSON(b, n.Alias)
+
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(n.Content[i].Value)
+			b.Write(key)
+			b.WriteByte(':')
+			if err := yamlToJSON(b, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+		return nil
+
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := yamlToJSON(b, c); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			b.WriteString("null")
+			return nil
+		case "!!bool":
+			var v bool
+			if err := n.Decode(&v); err != nil {
+				return err
+			}
+			fmt.Fprint(b, v)
+			return nil
+		case "!!int", "!!float":
+			if json.Valid([]byte(n.Value)) {
+				b.WriteString(n.Value)
+				return nil
+			}
+		}
+		value, _ := json.Marshal(n.Value)
+		b.Write(value)
+		return nil
+
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", n.Line)
+	}
+}
+
+// RunScenarios replays every scenario file matching pattern, such as
+// "testdata/*.yaml", as a subtest against a fresh instance of app.
+func RunScenarios(t *testing.T, app App, pattern string) {
+	t.Helper()
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatalf("tester: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("tester: no scenario matches %s", pattern)
+	}
+
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			s, err := LoadScenario(path)
+			if err != nil {
+				t.Fatalf("tester: %v", err)
+			}
+			New(t, app).Replay(s)
+		})
+	}
+}
+
+// Replay sends the requests of s in order, checking the expectations of each
+// one. Failures name the step they come from.
+func (tt *Tester) Replay(s *Scenario) {
+	tt.t.Helper()
+
+	for i, step := range s.Steps {
+		name := s.Name + ": " + step.name(i)
+		res, err := tt.replay(step)
+		if err != nil {
+			tt.t.Fatalf("tester: %s: %v", name, err)
+		}
+		res.step = name
+		if err := step.Expect.check(tt, res); err != nil {
+			tt.t.Fatalf("tester: %s: %v", name, err)
+		}
+	}
+}
+
+// name names the i-th step, counting from 1, with its Name if it has one.
+func (step *Step) name(i int) string {
+	name := fmt.Sprintf("step %d", i+1)
+	if step.Name != "" {
+		name += " (" + step.Name + ")"
+	}
+	return name
+}
+
+// validate checks the inputs and vouchers of step, as replaying it does.
+func (step *Step) validate() error {
+	if step.Advance != nil && step.Advance.Input != nil {
+		if _, err := step.Advance.Input.inputType(); err != nil {
+			return err
+		}
+	}
+	if step.Inspect != nil && step.Inspect.Input != nil {
+		if _, err := step.Inspect.Input.inputType(); err != nil {
+			return err
+		}
+	}
+	for _, v := range step.Expect.Vouchers {
+		if v.Type == "" {
+			continue
+		}
+		if _, err := v.inputType(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tt *Tester) replay(step Step) (*Result, error) {
+	tt.t.Helper()
+
+	switch {
+	case step.Advance != nil && step.Inspect != nil:
+		return nil, fmt.Errorf("both advance and inspect are set")
+
+	case step.Advance != nil:
+		a := step.Advance
+		if a.BlockNumber != nil {
+			tt.BlockNumber = *a.BlockNumber
+		}
+		if a.BlockTimestamp != nil {
+			tt.BlockTimestamp = *a.BlockTimestamp
+		}
+
+		payload, sender := []byte(a.Payload), common.Address{}
+		if a.Input != nil {
+			var err error
+			if payload, sender, err = a.Input.encodeAdvance(); err != nil {
+				return nil, err
+			}
+		}
+		if a.Sender != nil {
+			sender = *a.Sender
+		}
+		return tt.Advance(sender, payload), nil
+
+	case step.Inspect != nil:
+		payload := []byte(step.Inspect.Payload)
+		if step.Inspect.Input != nil {
+			var err error
+			if payload, err = step.Inspect.Input.encodeInspect(); err != nil {
+				return nil, err
+			}
+		}
+		return tt.Inspect(payload), nil
+
+	default:
+		return nil, fmt.Errorf("neither advance nor inspect is set")
+	}
+}
+
+// encodeAdvance encodes in and returns it with its default sender, the
+// portal of a deposit and the zero address otherwise.
+func (in *Input) encodeAdvance() ([]byte, common.Address, error) {
+	typ, err := in.inputType()
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+
+	var input interface{}
+	var sender common.Address
+	receiver := common.Hash(in.Receiver)
+	switch typ {
+	case parser.InputTypeEtherDeposit:
+		sender = router.EtherPortal
+		input = &parser.EtherDeposit{Sender: in.Sender, Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC20Deposit:
+		sender = router.ERC20Portal
+		input = &parser.ERC20Deposit{Token: in.Token, Sender: in.Sender, Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC721Deposit:
+		sender = router.ERC721Portal
+		input = &parser.ERC721Deposit{Token: in.Token, Sender: in.Sender, TokenID: in.TokenID.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC1155SingleDeposit:
+		sender = router.ERC1155SinglePortal
+		input = &parser.ERC1155SingleDeposit{Token: in.Token, Sender: in.Sender, TokenID: in.TokenID.Int(), Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC1155BatchDeposit:
+		sender = router.ERC1155BatchPortal
+		input = &parser.ERC1155BatchDeposit{Token: in.Token, Sender: in.Sender, TokenIDs: ints(in.TokenIDs), Amounts: ints(in.Amounts), BaseLayerData: in.BaseLayerData, ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeEtherWithdrawal:
+		input = &parser.EtherWithdrawal{Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC20Withdrawal:
+		input = &parser.ERC20Withdrawal{Token: in.Token, Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC721Withdrawal:
+		input = &parser.ERC721Withdrawal{Token: in.Token, TokenID: in.TokenID.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC1155SingleWithdrawal:
+		input = &parser.ERC1155SingleWithdrawal{Token: in.Token, TokenID: in.TokenID.Int(), Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC1155BatchWithdrawal:
+		input = &parser.ERC1155BatchWithdrawal{Token: in.Token, TokenIDs: ints(in.TokenIDs), Amounts: ints(in.Amounts), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeEtherTransfer:
+		input = &parser.EtherTransfer{Receiver: receiver, Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC20Transfer:
+		input = &parser.ERC20Transfer{Token: in.Token, Receiver: receiver, Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC721Transfer:
+		input = &parser.ERC721Transfer{Token: in.Token, Receiver: receiver, TokenID: in.TokenID.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC1155SingleTransfer:
+		input = &parser.ERC1155SingleTransfer{Token: in.Token, Receiver: receiver, TokenID: in.TokenID.Int(), Amount: in.Amount.Int(), ExecLayerData: in.ExecLayerData}
+	case parser.InputTypeERC1155BatchTransfer:
+		input = &parser.ERC1155BatchTransfer{Token: in.Token, Receiver: receiver, TokenIDs: ints(in.TokenIDs), Amounts: ints(in.Amounts), ExecLayerData: in.ExecLayerData}
+	default:
+		return nil, common.Address{}, fmt.Errorf("%w: %s is not an advance", parser.ErrIncompatibleInput, typ)
+	}
+
+	payload, err := parser.EncodeAdvance(input)
+	return payload, sender, err
+}
+
+// encodeInspect encodes in as a ledger_getBalance or ledger_getTotalSupply
+// request.
+func (in *Input) encodeInspect() ([]byte, error) {
+	typ, err := in.inputType()
+	if err != nil {
+		return nil, err
+	}
+
+	account := common.Hash(in.Account)
+	switch typ {
+	case parser.InputTypeBalance, parser.InputTypeBalanceAccount:
+		return parser.EncodeBalanceRequest(account, common.Address{}, nil)
+	case parser.InputTypeBalanceAccountTokenAddress:
+		return parser.EncodeBalanceRequest(account, in.Token, nil)
+	case parser.InputTypeBalanceAccountTokenAddressID:
+		return parser.EncodeBalanceRequest(account, in.Token, in.TokenID.Int())
+	case parser.InputTypeSupply:
+		return parser.EncodeSupplyRequest(common.Address{}, nil)
+	case parser.InputTypeSupplyTokenAddress:
+		return parser.EncodeSupplyRequest(in.Token, nil)
+	case parser.InputTypeSupplyTokenAddressID:
+		return parser.EncodeSupplyRequest(in.Token, in.TokenID.Int())
+	default:
+		return nil, fmt.Errorf("%w: %s is not an inspect", parser.ErrIncompatibleInput, typ)
+	}
+}
+
+// inputType parses the type of in and checks that in has the fields it needs.
+func (in *Input) inputType() (parser.InputType, error) {
+	typ, err := parser.ParseInputType(in.Type)
+	if err != nil {
+		return 0, err
+	}
+	return typ, checkFields(typ, in.Amount, in.TokenID, in.TokenIDs, in.Amounts)
+}
+
+func (e *Expectation) check(tt *Tester, res *Result) error {
+	tt.t.Helper()
+
+	if e.Accepted != nil {
+		if *e.Accepted {
+			res.ExpectAccepted()
+		} else {
+			res.ExpectRejected()
+		}
+	}
+	if e.Exception != nil {
+		res.ExpectException(e.Exception)
+	}
+	for _, v := range e.Vouchers {
+		voucher, err := v.voucher(tt.AppContract)
+		if err != nil {
+			return err
+		}
+		res.ExpectVoucher(voucher)
+	}
+	for _, v := range e.DelegateCallVouchers {
+		res.ExpectDelegateCallVoucher(parser.EncodeDelegateCallVoucher(v.Destination, v.Payload))
+	}
+	for _, n := range e.Notices {
+		res.ExpectNotice(n)
+	}
+	for _, r := range e.Reports {
+		res.ExpectReport(r)
+	}
+	if o := e.Outputs; o != nil {
+		res.ExpectOutputs(o.Vouchers, o.DelegateCallVouchers, o.Notices)
+	}
+	return nil
+}
+
+func (v *VoucherExpectation) voucher(app common.Address) (*parser.Voucher, error) {
+	if v.Type == "" {
+		value := v.Value.Int()
+		if value == nil {
+			value = new(big.Int)
+		}
+		return &parser.Voucher{Destination: v.Destination, Value: value, Payload: v.Payload}, nil
+	}
+
+	typ, err := v.inputType()
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case parser.InputTypeEtherWithdrawal:
+		return parser.EncodeEtherVoucher(v.Receiver, v.Amount.Int()), nil
+	case parser.InputTypeERC20Withdrawal:
+		return parser.EncodeERC20Voucher(v.Token, v.Receiver, v.Amount.Int())
+	case parser.InputTypeERC721Withdrawal:
+		return parser.EncodeERC721Voucher(v.Token, app, v.Receiver, v.TokenID.Int())
+	case parser.InputTypeERC1155SingleWithdrawal:
+		return parser.EncodeERC1155SingleVoucher(v.Token, app, v.Receiver, v.TokenID.Int(), v.Amount.Int())
+	case parser.InputTypeERC1155BatchWithdrawal:
+		return parser.EncodeERC1155BatchVoucher(v.Token, app, v.Receiver, ints(v.TokenIDs), ints(v.Amounts))
+	default:
+		return nil, fmt.Errorf("%w: %s does not produce a voucher", parser.ErrIncompatibleInput, typ)
+	}
+}
+
+// inputType parses the withdrawal type of v and checks that v has the
+// fields it needs.
+func (v *VoucherExpectation) inputType() (parser.InputType, error) {
+	typ, err := parser.ParseInputType(v.Type)
+	if err != nil {
+		return 0, err
+	}
+	return typ, checkFields(typ, v.Amount, v.TokenID, v.TokenIDs, v.Amounts)
+}
+
+// checkFields checks that the amount and token ID inputs of type typ need
+// are set, and that the batch lists have no null entries.
+func checkFields(typ parser.InputType, amount, tokenID *Amount, tokenIDs, amounts []*Amount) error {
+	needsAmount, needsTokenID := false, false
+	switch typ {
+	case parser.InputTypeEtherDeposit, parser.InputTypeERC20Deposit,
+		parser.InputTypeEtherWithdrawal, parser.InputTypeERC20Withdrawal,
+		parser.InputTypeEtherTransfer, parser.InputTypeERC20Transfer:
+		needsAmount = true
+	case parser.InputTypeERC721Deposit, parser.InputTypeERC721Withdrawal, parser.InputTypeERC721Transfer,
+		parser.InputTypeBalanceAccountTokenAddressID, parser.InputTypeSupplyTokenAddressID:
+		needsTokenID = true
+	case parser.InputTypeERC1155SingleDeposit, parser.InputTypeERC1155SingleWithdrawal, parser.InputTypeERC1155SingleTransfer:
+		needsAmount, needsTokenID = true, true
+	}
+
+	if needsAmount && amount == nil {
+		return fmt.Errorf("%w: %s without an amount", ErrInvalidScenario, typ)
+	}
+	if needsTokenID && tokenID == nil {
+		return fmt.Errorf("%w: %s without a tokenId", ErrInvalidScenario, typ)
+	}
+	for i, id := range tokenIDs {
+		if id == nil {
+			return fmt.Errorf("%w: %s with a null tokenIds[%d]", ErrInvalidScenario, typ, i)
+		}
+	}
+	for i, a := range amounts {
+		if a == nil {
+			return fmt.Errorf("%w: %s with a null amounts[%d]", ErrInvalidScenario, typ, i)
+		}
+	}
+	return nil
+}
+
+func ints(amounts []*Amount) []*big.Int {
+	ns := make([]*big.Int, len(amounts))
+	for i, a := range amounts {
+		ns[i] = a.Int()
+	}
+	return ns
+}
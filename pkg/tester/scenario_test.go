@@ -0,0 +1,135 @@
+//go:build !riscv64
+
+package tester_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+	"github.com/henriquemarlon/rollingopher/pkg/tester"
+	"github.com/henriquemarlon/rollingopher/pkg/wallet"
+)
+
+// bank runs the wallet and answers ledger queries.
+func bank(r rollup.Interface, l *ledger.Ledger) error {
+	rt := router.New(r, l)
+	if err := wallet.New().Register(rt); err != nil {
+		return err
+	}
+	if err := rt.HandleLedgerInspect(); err != nil {
+		return err
+	}
+	return rt.Run()
+}
+
+func TestRunScenarios(t *testing.T) {
+	tester.RunScenarios(t, bank, "testdata/wallet.*")
+}
+
+func TestLoadScenario(t *testing.T) {
+	for _, path := range []string{"testdata/wallet.yaml", "testdata/wallet.json"} {
+		s, err := tester.LoadScenario(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Name == "" || len(s.Steps) != 4 {
+			t.Fatalf("%s: unexpected scenario %+v", path, s)
+		}
+	}
+
+	s, err := tester.LoadScenario("testdata/wallet.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount := s.Steps[0].Advance.Input.Amount.Int(); amount.String() != "1000000000000000000" {
+		t.Fatalf("expected 1e18, got %s", amount)
+	}
+	if s.Steps[2].Advance.BlockNumber == nil || *s.Steps[2].Advance.BlockNumber != 10 {
+		t.Fatal("expected the block number of the withdrawal")
+	}
+}
+
+func TestParseScenarioRejectsUnknownFields(t *testing.T) {
+	_, err := tester.ParseScenario([]byte("steps:\n  - advance: {payload: x}\n    expect: {acepted: true}\n"))
+	if err == nil || !strings.Contains(err.Error(), "acepted") {
+		t.Fatalf("expected the misspelled field to be rejected, got %v", err)
+	}
+}
+
+func TestReplayNamesTheFailingStep(t *testing.T) {
+	s, err := tester.ParseScenario([]byte(`
+name: echo
+steps:
+  - advance: {payload: hello}
+    expect: {notices: [hello]}
+  - name: wrong
+    advance: {payload: hello}
+    expect: {notices: [other]}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recorder{TB: t}
+	tester.New(rec, echo).Replay(s)
+	if len(rec.errors) != 1 || !strings.HasPrefix(rec.errors[0], "echo: step 2 (wrong): advance 1") {
+		t.Fatalf("expected a single failure in step 2, got %q", rec.errors)
+	}
+}
+
+func TestParseScenarioRequiresAmountsAndTokenIDs(t *testing.T) {
+	for path, want := range map[string]string{
+		"testdata/invalid/deposit-without-amount.yaml":   "step 2 (forgot the amount): invalid scenario: EtherDeposit without an amount",
+		"testdata/invalid/voucher-without-token-id.json": "step 2 (withdrawal): invalid scenario: ERC721Withdrawal without a tokenId",
+	} {
+		_, err := tester.LoadScenario(path)
+		if !errors.Is(err, tester.ErrInvalidScenario) || !strings.HasSuffix(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", path, want, err)
+		}
+	}
+}
+
+func TestReplayRequiresAmountsAndTokenIDs(t *testing.T) {
+	// Scenarios built without ParseScenario skip its checks, which replaying
+	// them does again instead of panicking on the missing values.
+	data, err := os.ReadFile("testdata/invalid/voucher-without-token-id.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var withoutTokenID tester.Scenario
+	if err := json.Unmarshal(data, &withoutTokenID); err != nil {
+		t.Fatal(err)
+	}
+	withoutAmount := tester.Scenario{
+		Name:  "withdrawal without an amount",
+		Steps: []tester.Step{{Advance: &tester.AdvanceStep{Input: &tester.Input{Type: "EtherWithdrawal"}}}},
+	}
+
+	for _, tc := range []struct {
+		scenario *tester.Scenario
+		want     string
+	}{
+		{&withoutTokenID, "voucher without a token ID: step 2 (withdrawal): invalid scenario: ERC721Withdrawal without a tokenId"},
+		{&withoutAmount, "withdrawal without an amount: step 1: invalid scenario: EtherWithdrawal without an amount"},
+	} {
+		rec := &recorder{TB: t}
+		// The failure ends the goroutine replaying the scenario, as
+		// t.Fatalf does.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			tester.New(rec, bank).Replay(tc.scenario)
+		}()
+		<-done
+
+		if len(rec.errors) != 1 || !strings.HasSuffix(rec.errors[0], tc.want) {
+			t.Errorf("expected a single %q failure, got %q", tc.want, rec.errors)
+		}
+	}
+}
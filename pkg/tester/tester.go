@@ -0,0 +1,188 @@
+//go:build !riscv64
+
+// Package tester runs an application in-process against the mock rollup and
+// ledger, feeding it one request at a time and checking what it produced.
+//
+//	func TestDeposit(t *testing.T) {
+//		tester.New(t, app).
+//			Advance(router.EtherPortal, deposit).
+//			ExpectAccepted()
+//	}
+//
+// ExpectGolden compares everything the app produced in a test with a reviewed
+// golden file, regenerated with go test -update-golden.
+package tester
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+)
+
+// Timeout bounds how long a single request may take before the test fails.
+var Timeout = 10 * time.Second
+
+// App builds an application over r and l and runs its handler loop, usually
+// by returning the result of router.Run. It is given the mock rollup, but
+// only through the Interface the application also uses on the machine.
+type App func(r rollup.Interface, l *ledger.Ledger) error
+
+// Tester drives an App. Advances get consecutive input indices and the chain
+// id, application address and block fields set on the Tester.
+type Tester struct {
+	ChainID        uint64
+	AppContract    common.Address
+	BlockNumber    uint64
+	BlockTimestamp uint64
+
+	t       testing.TB
+	rollup  *rollup.Rollup
+	ledger  *ledger.Ledger
+	done    chan error
+	exited  bool
+	index   uint64
+	results []*Result
+
+	invariants *invariants
+}
+
+// New starts app in the background over a fresh mock rollup and ledger. The
+// ledger is restored whenever an advance is rejected, as on the node. When
+// the test ends the input is closed and the app must return without error.
+func New(t testing.TB, app App) *Tester {
+	t.Helper()
+
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatalf("tester: failed to create rollup: %v", err)
+	}
+	l, err := ledger.New()
+	if err != nil {
+		t.Fatalf("tester: failed to create ledger: %v", err)
+	}
+
+	r.Track(l)
+
+	tt := &Tester{
+		ChainID:     31337,
+		AppContract: common.HexToAddress("0xab7528bb862fB57E8A2BCd567a2e929a0Be56a5e"),
+		t:           t,
+		rollup:      r,
+		ledger:      l,
+		done:        make(chan error, 1),
+	}
+	go func() {
+		tt.done <- app(r, l)
+	}()
+	t.Cleanup(tt.close)
+	return tt
+}
+
+func (tt *Tester) close() {
+	tt.t.Helper()
+
+	if tt.exited {
+		return
+	}
+	tt.rollup.CloseInput()
+	select {
+	case err := <-tt.done:
+		tt.exited = true
+		if err != nil {
+			tt.t.Errorf("tester: app failed: %v", err)
+		}
+	case <-time.After(Timeout):
+		tt.t.Errorf("tester: %v to exit", ErrTimeout)
+	}
+}
+
+func (tt *Tester) Rollup() *rollup.Rollup {
+	return tt.rollup
+}
+
+func (tt *Tester) Ledger() *ledger.Ledger {
+	return tt.ledger
+}
+
+// Results returns the result of every request sent so far, in order.
+func (tt *Tester) Results() []*Result {
+	return append([]*Result(nil), tt.results...)
+}
+
+// Advance sends payload from sender and waits for the app to finish it.
+func (tt *Tester) Advance(sender common.Address, payload []byte) *Result {
+	tt.t.Helper()
+
+	return tt.AdvanceWith(rollup.Metadata{
+		ChainID:        tt.ChainID,
+		AppContract:    tt.AppContract,
+		MsgSender:      sender,
+		BlockNumber:    tt.BlockNumber,
+		BlockTimestamp: tt.BlockTimestamp,
+		Index:          tt.index,
+	}, payload)
+}
+
+// AdvanceWith sends payload with the given metadata as is. The next Advance
+// continues from metadata.Index.
+func (tt *Tester) AdvanceWith(metadata rollup.Metadata, payload []byte) *Result {
+	tt.t.Helper()
+
+	tt.index = metadata.Index + 1
+	return tt.run(func() {
+		tt.rollup.Advance(&rollup.Advance{Metadata: metadata, Payload: payload})
+	})
+}
+
+// Inspect sends payload as an inspect request and waits for the app to
+// answer it.
+func (tt *Tester) Inspect(payload []byte) *Result {
+	tt.t.Helper()
+
+	return tt.run(func() {
+		tt.rollup.Inspect(&rollup.Inspect{Payload: payload})
+	})
+}
+
+// run queues a request and waits until the app finishes it.
+func (tt *Tester) run(queue func()) *Result {
+	tt.t.Helper()
+
+	n := len(tt.rollup.Inputs())
+	queue()
+	in, err := tt.wait(n)
+	if err != nil {
+		tt.t.Fatalf("tester: %v", err)
+	}
+
+	res := &Result{Input: in, t: tt.t}
+	tt.results = append(tt.results, res)
+	tt.checkLedger(res)
+	return res
+}
+
+// wait returns the n-th input served by the mock once it is finished.
+func (tt *Tester) wait(n int) (rollup.Input, error) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(Timeout)
+
+	for {
+		if inputs := tt.rollup.Inputs(); len(inputs) > n && inputs[n].Finished {
+			return inputs[n], nil
+		}
+
+		select {
+		case err := <-tt.done:
+			tt.exited = true
+			return rollup.Input{}, fmt.Errorf("%w: %v", ErrAppExited, err)
+		case <-timeout:
+			return rollup.Input{}, ErrTimeout
+		case <-ticker.C:
+		}
+	}
+}
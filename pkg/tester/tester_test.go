@@ -0,0 +1,288 @@
+//go:build !riscv64
+
+package tester_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+	"github.com/henriquemarlon/rollingopher/pkg/tester"
+)
+
+var (
+	alice       = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	destination = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+)
+
+// recorder records the failures reported through it instead of failing the
+// test. Fatalf still ends the calling goroutine, as testing.T does.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// echo answers an advance with a notice and a voucher holding its payload
+// and an inspect with a report, rejecting the payload "reject".
+func echo(r rollup.Interface, l *ledger.Ledger) error {
+	rt := router.New(r, l)
+	rt.HandleFallback(func(ctx *router.Context) error {
+		if string(ctx.Payload()) == "reject" {
+			return ctx.Reject("rejected")
+		}
+		if _, err := ctx.Notice(ctx.Payload()); err != nil {
+			return err
+		}
+		_, err := ctx.Voucher(&parser.Voucher{Destination: destination, Value: big.NewInt(1), Payload: ctx.Payload()})
+		return err
+	})
+	rt.HandleInspect(func(ctx *router.Context) error {
+		return ctx.Report(ctx.Payload())
+	})
+	return rt.Run()
+}
+
+func TestTester(t *testing.T) {
+	tt := tester.New(t, echo)
+
+	voucher := &parser.Voucher{Destination: destination, Value: big.NewInt(1), Payload: []byte("hello")}
+	res := tt.Advance(alice, []byte("hello")).
+		ExpectAccepted().
+		ExpectOutputs(1, 0, 1).
+		ExpectNotice([]byte("hello")).
+		ExpectVoucher(voucher)
+	if m := res.Advance.Metadata; m.Index != 0 || m.MsgSender != alice || m.ChainID != tt.ChainID {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+
+	tt.Advance(alice, []byte("reject")).ExpectRejected().ExpectReport([]byte("rejected"))
+	tt.Inspect([]byte("query")).ExpectAccepted().ExpectReport([]byte("query")).ExpectOutputs(0, 0, 0)
+
+	res = tt.AdvanceWith(rollup.Metadata{MsgSender: alice, Index: 10}, []byte("again")).ExpectAccepted()
+	if res.Advance.Index != 10 {
+		t.Fatalf("expected index 10, got %d", res.Advance.Index)
+	}
+	if res := tt.Advance(alice, []byte("next")); res.Advance.Index != 11 {
+		t.Fatalf("expected index 11, got %d", res.Advance.Index)
+	}
+	if n := len(tt.Results()); n != 5 {
+		t.Fatalf("expected 5 results, got %d", n)
+	}
+}
+
+func TestOutputIndices(t *testing.T) {
+	tt := tester.New(t, echo)
+
+	first := tt.Advance(alice, []byte("a")).ExpectOutputs(1, 0, 1)
+	second := tt.Advance(alice, []byte("b")).ExpectOutputs(1, 0, 1)
+	if first.Notices[0].Index != 0 || first.Vouchers[0].Index != 1 {
+		t.Fatalf("unexpected indices in %+v", first.Input)
+	}
+	if second.Notices[0].Index != 2 || second.Vouchers[0].Index != 3 {
+		t.Fatalf("unexpected indices in %+v", second.Input)
+	}
+}
+
+func TestExpectVoucherWithoutValue(t *testing.T) {
+	tt := tester.New(t, func(r rollup.Interface, l *ledger.Ledger) error {
+		rt := router.New(r, l)
+		rt.HandleFallback(func(ctx *router.Context) error {
+			_, err := ctx.Voucher(&parser.Voucher{Destination: destination, Payload: ctx.Payload()})
+			return err
+		})
+		return rt.Run()
+	})
+
+	tt.Advance(alice, []byte("call")).
+		ExpectAccepted().
+		ExpectVoucher(&parser.Voucher{Destination: destination, Payload: []byte("call")}).
+		ExpectVoucher(&parser.Voucher{Destination: destination, Value: new(big.Int), Payload: []byte("call")})
+}
+
+func TestException(t *testing.T) {
+	var afterException error
+	tt := tester.New(t, func(r rollup.Interface, l *ledger.Ledger) error {
+		rt := router.New(r, l)
+		rt.HandleFallback(func(ctx *router.Context) error {
+			if _, err := ctx.Notice(ctx.Payload()); err != nil {
+				return err
+			}
+			if string(ctx.Payload()) == "raise" {
+				if err := r.EmitException([]byte("boom")); err != nil {
+					return err
+				}
+				_, afterException = ctx.Notice([]byte("too late"))
+			}
+			return nil
+		})
+		return rt.Run()
+	})
+
+	res := tt.Advance(alice, []byte("raise")).ExpectRejected().ExpectException([]byte("boom")).ExpectOutputs(0, 0, 0)
+	if !res.Finished {
+		t.Fatal("expected the exception to finish the input")
+	}
+	if !errors.Is(afterException, rollup.ErrAlreadyFinished) {
+		t.Fatalf("expected %v emitting after the exception, got %v", rollup.ErrAlreadyFinished, afterException)
+	}
+	tt.Advance(alice, []byte("next")).ExpectAccepted().ExpectNotice([]byte("next"))
+}
+
+func TestRejectedAdvancesDiscardOutputsAndLedgerChanges(t *testing.T) {
+	tt := tester.New(t, func(r rollup.Interface, l *ledger.Ledger) error {
+		rt := router.New(r, l)
+		rt.HandleFallback(func(ctx *router.Context) error {
+			assetID, err := ctx.Ledger.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFindOrCreate)
+			if err != nil {
+				return err
+			}
+			accountID, err := ctx.Ledger.RetrieveAccountByAddress(ctx.Sender(), ledger.RetrieveOperationFindOrCreate)
+			if err != nil {
+				return err
+			}
+			if err := ctx.Ledger.Deposit(assetID, accountID, big.NewInt(1)); err != nil {
+				return err
+			}
+			if _, err := ctx.Notice([]byte("deposited")); err != nil {
+				return err
+			}
+			switch string(ctx.Payload()) {
+			case "reject":
+				return ctx.Reject("rejected on request")
+			case "raise":
+				return r.EmitException([]byte("boom"))
+			}
+			return nil
+		})
+		return rt.Run()
+	})
+
+	tt.Advance(alice, []byte("accept")).ExpectAccepted().ExpectOutputs(0, 0, 1)
+	tt.Advance(alice, []byte("reject")).ExpectRejected().ExpectOutputs(0, 0, 0).ExpectReport([]byte("rejected on request"))
+	tt.Advance(alice, []byte("raise")).ExpectRejected().ExpectOutputs(0, 0, 0).ExpectException([]byte("boom"))
+
+	// The indices of the discarded notices are reused.
+	res := tt.Advance(alice, []byte("accept")).ExpectAccepted().ExpectOutputs(0, 0, 1)
+	if res.Notices[0].Index != 1 {
+		t.Fatalf("expected output index 1, got %d", res.Notices[0].Index)
+	}
+	if n := len(tt.Rollup().Notices()); n != 2 {
+		t.Fatalf("expected the notices of rejected advances to be discarded, got %d notices", n)
+	}
+
+	assetID, err := tt.Ledger().RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supply, err := tt.Ledger().GetTotalSupply(assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if supply.Int64() != 2 {
+		t.Fatalf("expected the deposits of rejected advances to be reverted, got a supply of %s", supply)
+	}
+}
+
+func TestExpectFailures(t *testing.T) {
+	rec := &recorder{TB: t}
+	tt := tester.New(rec, echo)
+
+	tt.Advance(alice, []byte("hello")).
+		ExpectRejected().
+		ExpectOutputs(0, 0, 0).
+		ExpectNotice([]byte("other")).
+		ExpectVoucher(&parser.Voucher{Destination: destination, Value: big.NewInt(2), Payload: []byte("hello")}).
+		ExpectReport([]byte("hello")).
+		ExpectException([]byte("hello"))
+	tt.Advance(alice, []byte("reject")).ExpectAccepted()
+
+	if len(rec.errors) != 7 {
+		t.Fatalf("expected 7 failures, got %d: %q", len(rec.errors), rec.errors)
+	}
+}
+
+func TestAppExit(t *testing.T) {
+	errStop := errors.New("stopped")
+
+	rec := &recorder{TB: t}
+	tt := tester.New(rec, func(r rollup.Interface, l *ledger.Ledger) error {
+		return errStop
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tt.Advance(alice, []byte("x"))
+	}()
+	<-done
+
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], tester.ErrAppExited.Error()+": "+errStop.Error()) {
+		t.Fatalf("expected the test to fail when the app exits, got %q", rec.errors)
+	}
+}
+
+// sendEcho sends the requests of the echo golden file.
+func sendEcho(tt *tester.Tester, last string) {
+	tt.Advance(alice, []byte("hello"))
+	tt.Advance(alice, []byte("reject"))
+	tt.Advance(alice, []byte{0xff, 0x00})
+	tt.Inspect([]byte(last))
+}
+
+func TestExpectGolden(t *testing.T) {
+	tt := tester.New(t, echo)
+	sendEcho(tt, "query")
+	tt.ExpectGolden("echo")
+
+	// A mismatching run would overwrite the file when updating it.
+	if flag.Lookup("update-golden").Value.String() == "true" {
+		return
+	}
+	rec := &recorder{TB: t}
+	tt = tester.New(rec, echo)
+	sendEcho(tt, "other")
+	tt.ExpectGolden("echo")
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "differs at line 20") {
+		t.Fatalf("expected a single difference at line 20, got %q", rec.errors)
+	}
+}
+
+func TestExpectGoldenUpdate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := flag.Set("update-golden", "true"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { flag.Set("update-golden", "false") })
+
+	tt := tester.New(t, echo)
+	sendEcho(tt, "query")
+	tt.ExpectGolden("echo")
+
+	got, err := os.ReadFile(filepath.Join("testdata", "echo.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != tt.Transcript() {
+		t.Fatalf("expected the golden file to hold the transcript, got:\n%s", got)
+	}
+}
@@ -0,0 +1,388 @@
+package wallet
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+)
+
+// Hook is called after a deposit, withdrawal or transfer has been applied to
+// the ledger, with the decoded input (e.g. *parser.EtherDeposit). Returning an
+// error rejects the input, reverting the operation.
+type Hook func(ctx *router.Context, input interface{}) error
+
+// Wallet handles deposits from every portal and the ledger withdrawal and
+// transfer inputs understood by parser.DecodeAdvanceAuto. Assets under
+// router.ReservedToken are refused. Any failure rejects the input and emits a
+// report describing it.
+type Wallet struct {
+	OnDeposit    Hook
+	OnWithdrawal Hook
+	OnTransfer   Hook
+}
+
+func New() *Wallet {
+	return &Wallet{}
+}
+
+// Register installs the wallet handlers on rt, which must have a ledger.
+func (w *Wallet) Register(rt *router.Router, mws ...router.Middleware) error {
+	if rt.Ledger() == nil {
+		return router.ErrNoLedger
+	}
+
+	rt.HandleEtherDeposit(w.etherDeposit, mws...)
+	rt.HandleERC20Deposit(w.erc20Deposit, mws...)
+	rt.HandleERC721Deposit(w.erc721Deposit, mws...)
+	rt.HandleERC1155SingleDeposit(w.erc1155SingleDeposit, mws...)
+	rt.HandleERC1155BatchDeposit(w.erc1155BatchDeposit, mws...)
+
+	selectors := []uint32{
+		parser.SelectorWithdrawEther,
+		parser.SelectorWithdrawERC20,
+		parser.SelectorWithdrawERC721,
+		parser.SelectorWithdrawERC1155Single,
+		parser.SelectorWithdrawERC1155Batch,
+		parser.SelectorTransferEther,
+		parser.SelectorTransferERC20,
+		parser.SelectorTransferERC721,
+		parser.SelectorTransferERC1155Single,
+		parser.SelectorTransferERC1155Batch,
+	}
+	for _, selector := range selectors {
+		rt.HandleSelector(selector, w.ledgerOperation, mws...)
+	}
+	return nil
+}
+
+// Deposits
+
+func (w *Wallet) etherDeposit(ctx *router.Context, d *parser.EtherDeposit) error {
+	assetID, err := ctx.Ledger.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return fail(ctx, "ether deposit", err)
+	}
+	if err := deposit(ctx.Ledger, assetID, d.Sender, d.Amount); err != nil {
+		return fail(ctx, "ether deposit", err)
+	}
+	log.Printf("[wallet] %s deposited %s ether", d.Sender.Hex(), d.Amount)
+	return w.hook(ctx, w.OnDeposit, d)
+}
+
+func (w *Wallet) erc20Deposit(ctx *router.Context, d *parser.ERC20Deposit) error {
+	if err := notReserved(d.Token); err != nil {
+		return fail(ctx, "ERC20 deposit", err)
+	}
+	assetID, err := ctx.Ledger.RetrieveAsset(d.Token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return fail(ctx, "ERC20 deposit", err)
+	}
+	if err := deposit(ctx.Ledger, assetID, d.Sender, d.Amount); err != nil {
+		return fail(ctx, "ERC20 deposit", err)
+	}
+	log.Printf("[wallet] %s deposited %s of %s", d.Sender.Hex(), d.Amount, d.Token.Hex())
+	return w.hook(ctx, w.OnDeposit, d)
+}
+
+func (w *Wallet) erc721Deposit(ctx *router.Context, d *parser.ERC721Deposit) error {
+	if err := notReserved(d.Token); err != nil {
+		return fail(ctx, "ERC721 deposit", err)
+	}
+	assetID, err := ctx.Ledger.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return fail(ctx, "ERC721 deposit", err)
+	}
+	if err := deposit(ctx.Ledger, assetID, d.Sender, big.NewInt(1)); err != nil {
+		return fail(ctx, "ERC721 deposit", err)
+	}
+	log.Printf("[wallet] %s deposited ERC721 %s #%s", d.Sender.Hex(), d.Token.Hex(), d.TokenID)
+	return w.hook(ctx, w.OnDeposit, d)
+}
+
+func (w *Wallet) erc1155SingleDeposit(ctx *router.Context, d *parser.ERC1155SingleDeposit) error {
+	if err := notReserved(d.Token); err != nil {
+		return fail(ctx, "ERC1155 deposit", err)
+	}
+	assetID, err := ctx.Ledger.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return fail(ctx, "ERC1155 deposit", err)
+	}
+	if err := deposit(ctx.Ledger, assetID, d.Sender, d.Amount); err != nil {
+		return fail(ctx, "ERC1155 deposit", err)
+	}
+	log.Printf("[wallet] %s deposited %s of ERC1155 %s #%s", d.Sender.Hex(), d.Amount, d.Token.Hex(), d.TokenID)
+	return w.hook(ctx, w.OnDeposit, d)
+}
+
+func (w *Wallet) erc1155BatchDeposit(ctx *router.Context, d *parser.ERC1155BatchDeposit) error {
+	if err := notReserved(d.Token); err != nil {
+		return fail(ctx, "ERC1155 batch deposit", err)
+	}
+	if len(d.TokenIDs) != len(d.Amounts) {
+		return fail(ctx, "ERC1155 batch deposit", parser.ErrMalformedInput)
+	}
+	if err := rollup.ForEach(ctx, len(d.TokenIDs), func(i int) error {
+		assetID, err := ctx.Ledger.RetrieveAsset(d.Token, d.TokenIDs[i], ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFindOrCreate)
+		if err != nil {
+			return err
+		}
+		return deposit(ctx.Ledger, assetID, d.Sender, d.Amounts[i])
+	}); err != nil {
+		return fail(ctx, "ERC1155 batch deposit", err)
+	}
+	log.Printf("[wallet] %s deposited ERC1155 batch from %s", d.Sender.Hex(), d.Token.Hex())
+	return w.hook(ctx, w.OnDeposit, d)
+}
+
+// Withdrawals and transfers
+
+func (w *Wallet) ledgerOperation(ctx *router.Context) error {
+	input, err := parser.DecodeAdvanceAuto(ctx.Payload())
+	if err != nil {
+		return fail(ctx, "decode", err)
+	}
+
+	sender := ctx.Sender()
+	l := ctx.Ledger
+	app := ctx.Metadata.AppContract
+
+	switch d := input.(type) {
+	case *parser.EtherWithdrawal:
+		assetID, err := l.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ether withdrawal", err)
+		}
+		if err := withdraw(l, assetID, sender, d.Amount); err != nil {
+			return fail(ctx, "ether withdrawal", err)
+		}
+		if _, err := ctx.Voucher(parser.EncodeEtherVoucher(sender, d.Amount)); err != nil {
+			return fail(ctx, "ether withdrawal", err)
+		}
+		log.Printf("[wallet] %s withdrew %s ether", sender.Hex(), d.Amount)
+		return w.hook(ctx, w.OnWithdrawal, d)
+
+	case *parser.ERC20Withdrawal:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC20 withdrawal", err)
+		}
+		assetID, err := l.RetrieveAsset(d.Token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ERC20 withdrawal", err)
+		}
+		if err := withdraw(l, assetID, sender, d.Amount); err != nil {
+			return fail(ctx, "ERC20 withdrawal", err)
+		}
+		if err := emitVoucher(ctx, func() (*parser.Voucher, error) {
+			return parser.EncodeERC20Voucher(d.Token, sender, d.Amount)
+		}); err != nil {
+			return fail(ctx, "ERC20 withdrawal", err)
+		}
+		log.Printf("[wallet] %s withdrew %s of %s", sender.Hex(), d.Amount, d.Token.Hex())
+		return w.hook(ctx, w.OnWithdrawal, d)
+
+	case *parser.ERC721Withdrawal:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC721 withdrawal", err)
+		}
+		assetID, err := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ERC721 withdrawal", err)
+		}
+		if err := withdraw(l, assetID, sender, big.NewInt(1)); err != nil {
+			return fail(ctx, "ERC721 withdrawal", err)
+		}
+		if err := emitVoucher(ctx, func() (*parser.Voucher, error) {
+			return parser.EncodeERC721Voucher(d.Token, app, sender, d.TokenID)
+		}); err != nil {
+			return fail(ctx, "ERC721 withdrawal", err)
+		}
+		log.Printf("[wallet] %s withdrew ERC721 %s #%s", sender.Hex(), d.Token.Hex(), d.TokenID)
+		return w.hook(ctx, w.OnWithdrawal, d)
+
+	case *parser.ERC1155SingleWithdrawal:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC1155 withdrawal", err)
+		}
+		assetID, err := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ERC1155 withdrawal", err)
+		}
+		if err := withdraw(l, assetID, sender, d.Amount); err != nil {
+			return fail(ctx, "ERC1155 withdrawal", err)
+		}
+		if err := emitVoucher(ctx, func() (*parser.Voucher, error) {
+			return parser.EncodeERC1155SingleVoucher(d.Token, app, sender, d.TokenID, d.Amount)
+		}); err != nil {
+			return fail(ctx, "ERC1155 withdrawal", err)
+		}
+		log.Printf("[wallet] %s withdrew %s of ERC1155 %s #%s", sender.Hex(), d.Amount, d.Token.Hex(), d.TokenID)
+		return w.hook(ctx, w.OnWithdrawal, d)
+
+	case *parser.ERC1155BatchWithdrawal:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC1155 batch withdrawal", err)
+		}
+		if len(d.TokenIDs) != len(d.Amounts) {
+			return fail(ctx, "ERC1155 batch withdrawal", parser.ErrMalformedInput)
+		}
+		if err := rollup.ForEach(ctx, len(d.TokenIDs), func(i int) error {
+			assetID, err := l.RetrieveAsset(d.Token, d.TokenIDs[i], ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+			if err != nil {
+				return err
+			}
+			return withdraw(l, assetID, sender, d.Amounts[i])
+		}); err != nil {
+			return fail(ctx, "ERC1155 batch withdrawal", err)
+		}
+		if err := emitVoucher(ctx, func() (*parser.Voucher, error) {
+			return parser.EncodeERC1155BatchVoucher(d.Token, app, sender, d.TokenIDs, d.Amounts)
+		}); err != nil {
+			return fail(ctx, "ERC1155 batch withdrawal", err)
+		}
+		log.Printf("[wallet] %s withdrew ERC1155 batch from %s", sender.Hex(), d.Token.Hex())
+		return w.hook(ctx, w.OnWithdrawal, d)
+
+	case *parser.EtherTransfer:
+		assetID, err := l.RetrieveAsset(common.Address{}, nil, ledger.AssetTypeID, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ether transfer", err)
+		}
+		if err := transfer(l, assetID, sender, d.Receiver, d.Amount); err != nil {
+			return fail(ctx, "ether transfer", err)
+		}
+		log.Printf("[wallet] %s transferred %s ether to %s", sender.Hex(), d.Amount, d.Receiver.Hex())
+		return w.hook(ctx, w.OnTransfer, d)
+
+	case *parser.ERC20Transfer:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC20 transfer", err)
+		}
+		assetID, err := l.RetrieveAsset(d.Token, nil, ledger.AssetTypeTokenAddress, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ERC20 transfer", err)
+		}
+		if err := transfer(l, assetID, sender, d.Receiver, d.Amount); err != nil {
+			return fail(ctx, "ERC20 transfer", err)
+		}
+		log.Printf("[wallet] %s transferred %s of %s to %s", sender.Hex(), d.Amount, d.Token.Hex(), d.Receiver.Hex())
+		return w.hook(ctx, w.OnTransfer, d)
+
+	case *parser.ERC721Transfer:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC721 transfer", err)
+		}
+		assetID, err := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ERC721 transfer", err)
+		}
+		if err := transfer(l, assetID, sender, d.Receiver, big.NewInt(1)); err != nil {
+			return fail(ctx, "ERC721 transfer", err)
+		}
+		log.Printf("[wallet] %s transferred ERC721 %s #%s to %s", sender.Hex(), d.Token.Hex(), d.TokenID, d.Receiver.Hex())
+		return w.hook(ctx, w.OnTransfer, d)
+
+	case *parser.ERC1155SingleTransfer:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC1155 transfer", err)
+		}
+		assetID, err := l.RetrieveAsset(d.Token, d.TokenID, ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+		if err != nil {
+			return fail(ctx, "ERC1155 transfer", err)
+		}
+		if err := transfer(l, assetID, sender, d.Receiver, d.Amount); err != nil {
+			return fail(ctx, "ERC1155 transfer", err)
+		}
+		log.Printf("[wallet] %s transferred %s of ERC1155 %s #%s to %s", sender.Hex(), d.Amount, d.Token.Hex(), d.TokenID, d.Receiver.Hex())
+		return w.hook(ctx, w.OnTransfer, d)
+
+	case *parser.ERC1155BatchTransfer:
+		if err := notReserved(d.Token); err != nil {
+			return fail(ctx, "ERC1155 batch transfer", err)
+		}
+		if len(d.TokenIDs) != len(d.Amounts) {
+			return fail(ctx, "ERC1155 batch transfer", parser.ErrMalformedInput)
+		}
+		if err := rollup.ForEach(ctx, len(d.TokenIDs), func(i int) error {
+			assetID, err := l.RetrieveAsset(d.Token, d.TokenIDs[i], ledger.AssetTypeTokenAddressID, ledger.RetrieveOperationFind)
+			if err != nil {
+				return err
+			}
+			return transfer(l, assetID, sender, d.Receiver, d.Amounts[i])
+		}); err != nil {
+			return fail(ctx, "ERC1155 batch transfer", err)
+		}
+		log.Printf("[wallet] %s transferred ERC1155 batch of %s to %s", sender.Hex(), d.Token.Hex(), d.Receiver.Hex())
+		return w.hook(ctx, w.OnTransfer, d)
+
+	default:
+		return fail(ctx, "decode", parser.ErrUnknownInputType)
+	}
+}
+
+func (w *Wallet) hook(ctx *router.Context, hook Hook, input interface{}) error {
+	if hook == nil {
+		return nil
+	}
+	return hook(ctx, input)
+}
+
+func deposit(l *ledger.Ledger, assetID ledger.AssetID, account common.Address, amount *big.Int) error {
+	accountID, err := l.RetrieveAccountByAddress(account, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	return l.Deposit(assetID, accountID, amount)
+}
+
+func withdraw(l *ledger.Ledger, assetID ledger.AssetID, account common.Address, amount *big.Int) error {
+	accountID, err := l.RetrieveAccountByAddress(account, ledger.RetrieveOperationFind)
+	if err != nil {
+		return err
+	}
+	return l.Withdraw(assetID, accountID, amount)
+}
+
+func transfer(l *ledger.Ledger, assetID ledger.AssetID, from common.Address, to common.Hash, amount *big.Int) error {
+	fromID, err := l.RetrieveAccountByAddress(from, ledger.RetrieveOperationFind)
+	if err != nil {
+		return err
+	}
+	toID, err := l.RetrieveAccountByID(to, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		return err
+	}
+	return l.Transfer(assetID, fromID, toID, amount)
+}
+
+// notReserved refuses router.ReservedToken.
+func notReserved(token common.Address) error {
+	if token == router.ReservedToken {
+		return fmt.Errorf("%w: %s", router.ErrReservedToken, token.Hex())
+	}
+	return nil
+}
+
+func emitVoucher(ctx *router.Context, encode func() (*parser.Voucher, error)) error {
+	v, err := encode()
+	if err != nil {
+		return err
+	}
+	_, err = ctx.Voucher(v)
+	return err
+}
+
+// fail reports err and returns it, keeping the underlying ledger or parser
+// error available to errors.Is.
+func fail(ctx *router.Context, op string, err error) error {
+	err = fmt.Errorf("%s: %w", op, err)
+	if rerr := ctx.Report([]byte(err.Error())); rerr != nil {
+		log.Printf("[wallet] failed to emit report: %v", rerr)
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+//go:build !riscv64
+
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/henriquemarlon/rollingopher/pkg/ledger"
+	"github.com/henriquemarlon/rollingopher/pkg/parser"
+	"github.com/henriquemarlon/rollingopher/pkg/rollup"
+	"github.com/henriquemarlon/rollingopher/pkg/router"
+)
+
+var (
+	alice = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	bob   = common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	token = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+)
+
+// env is a mock rollup and ledger the wallet handlers are called against.
+type env struct {
+	t *testing.T
+	r *rollup.Rollup
+	l *ledger.Ledger
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ledger.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &env{t: t, r: r, l: l}
+}
+
+func (e *env) context(sender common.Address, payload []byte) *router.Context {
+	return router.NewAdvanceContext(e.r, e.l, &rollup.Advance{Metadata: rollup.Metadata{MsgSender: sender}, Payload: payload})
+}
+
+func (e *env) balance(account common.Address, tokenID *big.Int) *big.Int {
+	e.t.Helper()
+
+	assetType := ledger.AssetTypeTokenAddress
+	if tokenID != nil {
+		assetType = ledger.AssetTypeTokenAddressID
+	}
+	assetID, err := e.l.RetrieveAsset(token, tokenID, assetType, ledger.RetrieveOperationFind)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	accountID, err := e.l.RetrieveAccountByAddress(account, ledger.RetrieveOperationFindOrCreate)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	balance, err := e.l.GetBalance(assetID, accountID)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return balance
+}
+
+// expectFailure checks that err wraps want and was reported.
+func (e *env) expectFailure(err, want error) {
+	e.t.Helper()
+
+	if !errors.Is(err, want) {
+		e.t.Fatalf("expected %v, got %v", want, err)
+	}
+	reports := e.r.Reports()
+	if len(reports) == 0 || !bytes.Contains(reports[len(reports)-1].Payload, []byte(want.Error())) {
+		e.t.Fatalf("expected a report mentioning %q, got %q", want, reports)
+	}
+}
+
+func TestERC20DepositTransferAndWithdrawal(t *testing.T) {
+	e := newEnv(t)
+	w := New()
+
+	if err := w.erc20Deposit(e.context(router.ERC20Portal, nil), &parser.ERC20Deposit{Token: token, Sender: alice, Amount: big.NewInt(10)}); err != nil {
+		t.Fatal(err)
+	}
+	transfer := parser.EncodeERC20Transfer(&parser.ERC20Transfer{Token: token, Receiver: common.BytesToHash(bob.Bytes()), Amount: big.NewInt(4)})
+	if err := w.ledgerOperation(e.context(alice, transfer)); err != nil {
+		t.Fatal(err)
+	}
+	withdrawal := parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: token, Amount: big.NewInt(3)})
+	if err := w.ledgerOperation(e.context(bob, withdrawal)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := e.balance(alice, nil); got.Int64() != 6 {
+		t.Errorf("expected alice to hold 6, got %s", got)
+	}
+	if got := e.balance(bob, nil); got.Int64() != 1 {
+		t.Errorf("expected bob to hold 1, got %s", got)
+	}
+}
+
+func TestERC1155BatchDeposit(t *testing.T) {
+	e := newEnv(t)
+	w := New()
+
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	d := &parser.ERC1155BatchDeposit{Token: token, Sender: alice, TokenIDs: ids, Amounts: []*big.Int{big.NewInt(5), big.NewInt(6)}}
+	if err := w.erc1155BatchDeposit(e.context(router.ERC1155BatchPortal, nil), d); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.balance(alice, ids[1]); got.Int64() != 6 {
+		t.Errorf("expected alice to hold 6 of token 2, got %s", got)
+	}
+
+	d.Amounts = d.Amounts[:1]
+	e.expectFailure(w.erc1155BatchDeposit(e.context(router.ERC1155BatchPortal, nil), d), parser.ErrMalformedInput)
+}
+
+func TestErrorPaths(t *testing.T) {
+	e := newEnv(t)
+	w := New()
+	if err := w.erc20Deposit(e.context(router.ERC20Portal, nil), &parser.ERC20Deposit{Token: token, Sender: alice, Amount: big.NewInt(10)}); err != nil {
+		t.Fatal(err)
+	}
+
+	other := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+	for _, tc := range []struct {
+		sender  common.Address
+		payload []byte
+		want    error
+	}{
+		{alice, parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: other, Amount: big.NewInt(1)}), ledger.ErrAssetNotFound},
+		{bob, parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: token, Amount: big.NewInt(1)}), ledger.ErrAccountNotFound},
+		{alice, parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: token, Amount: big.NewInt(11)}), ledger.ErrInsufficientFunds},
+		{alice, parser.EncodeERC20Transfer(&parser.ERC20Transfer{Token: token, Receiver: common.BytesToHash(bob.Bytes()), Amount: big.NewInt(11)}), ledger.ErrInsufficientFunds},
+		{alice, parser.EncodeEtherWithdrawal(&parser.EtherWithdrawal{Amount: big.NewInt(1)}), ledger.ErrAssetNotFound},
+		{alice, parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: token, Amount: big.NewInt(1)})[:40], parser.ErrMalformedInput},
+		{alice, parser.EncodeERC20Withdrawal(&parser.ERC20Withdrawal{Token: router.ReservedToken, Amount: big.NewInt(1)}), router.ErrReservedToken},
+		{alice, parser.EncodeERC1155SingleTransfer(&parser.ERC1155SingleTransfer{Token: router.ReservedToken, Receiver: common.BytesToHash(bob.Bytes()), TokenID: big.NewInt(1), Amount: big.NewInt(1)}), router.ErrReservedToken},
+	} {
+		e.expectFailure(w.ledgerOperation(e.context(tc.sender, tc.payload)), tc.want)
+	}
+
+	e.expectFailure(w.erc20Deposit(e.context(router.ERC20Portal, nil), &parser.ERC20Deposit{Token: router.ReservedToken, Sender: alice, Amount: big.NewInt(1)}), router.ErrReservedToken)
+
+	if got := e.balance(alice, nil); got.Int64() != 10 {
+		t.Errorf("expected failed operations to leave alice with 10, got %s", got)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	e := newEnv(t)
+	errHook := errors.New("hook refused")
+
+	var got interface{}
+	w := New()
+	w.OnDeposit = func(ctx *router.Context, input interface{}) error {
+		got = input
+		return errHook
+	}
+
+	d := &parser.ERC20Deposit{Token: token, Sender: alice, Amount: big.NewInt(1)}
+	if err := w.erc20Deposit(e.context(router.ERC20Portal, nil), d); !errors.Is(err, errHook) {
+		t.Fatalf("expected the hook error, got %v", err)
+	}
+	if got != d {
+		t.Fatalf("expected the hook to receive the deposit, got %v", got)
+	}
+}
+
+func TestRegisterRequiresLedger(t *testing.T) {
+	r, err := rollup.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New().Register(router.New(r, nil)); !errors.Is(err, router.ErrNoLedger) {
+		t.Fatalf("expected %v, got %v", router.ErrNoLedger, err)
+	}
+}